@@ -1,117 +1,115 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"math/rand"
-	"net"
-	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"runtime"
-	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/viper"
 
-	"github.com/mpkondrashin/certalert/pkg/secureftp"
-	"github.com/mpkondrashin/certalert/pkg/sms"
+	"github.com/mpkondrashin/certlist/pkg/backend"
+	"github.com/mpkondrashin/certlist/pkg/certlist"
 	"github.com/mpkondrashin/certlist/pkg/config"
-	"github.com/mpkondrashin/certlist/pkg/maria"
-	"github.com/mpkondrashin/certlist/pkg/smsbackup"
 )
 
-func GetSMS() *sms.SMS {
-	auth := sms.NewAPIKeyAuthorization(viper.GetString(config.SMSAPIKey))
-	smsClient := sms.New("https://"+viper.GetString(config.SMSAddress), auth)
-	return smsClient.SetInsecureSkipVerify(viper.GetBool(config.SMSIgnoreTLSErrors))
-}
-
-func GetLocalAddress() string {
-	smsAddress := viper.GetString(config.SMSAddress)
-	log.Printf("Dial SMS (%s)", smsAddress)
-	localIP, err := GetOutboundIP(smsAddress + ":443")
+// GetSMSOptions returns list of SMS servers from configuration.
+func GetSMSOptions() (result []certlist.SMSOptions) {
+	servers, err := config.GetSMSServers()
 	if err != nil {
-		log.Fatal(err)
+		Panic("%s: %v", config.SMSServers, err)
 	}
-	log.Printf("SMS connection succeeded")
-	log.Printf("Local address %v", localIP)
-	return localIP.String()
-}
-
-func GetBackupFileName() string {
-	backupBaseName := strings.ToLower(RandStringBytesRmndr(16))
-	return backupBaseName + ".gz"
+	for _, server := range servers {
+		result = append(result, certlist.SMSOptions{
+			Address:         server.Address,
+			APIKey:          server.APIKey,
+			IgnoreTLSErrors: server.IgnoreTLSErrors,
+		})
+	}
+	return
 }
 
-func FilterBackupPath(backupPath string) string {
-	if runtime.GOOS != "windows" {
-		return backupPath
-	}
-	path, err := os.Getwd()
+// GetPolicyOptions returns policy rules and findings report filename from configuration.
+func GetPolicyOptions() certlist.PolicyOptions {
+	rules, err := config.GetPolicyRules()
 	if err != nil {
-		log.Fatal(err)
+		Panic("%s: %v", config.PolicyRules, err)
 	}
-	currentDrive := path[:2]
-	if !strings.HasPrefix(backupPath, currentDrive) {
-		Panic("TEMP is on %s drive and not on current drive: %s", backupPath[:2], currentDrive)
+	return certlist.PolicyOptions{
+		Rules:    rules,
+		Findings: viper.GetString(config.PolicyFindings),
 	}
-	backupPath = backupPath[2:]
-	return strings.ReplaceAll(backupPath, "\\", "/")
 }
 
-func RunBackup(smsClient *sms.SMS, username, password, localIP, backupPath string) {
-	backupPath = FilterBackupPath(backupPath)
-	//log.Printf("RunBackup(%v, %s, %s, %s, %s)", smsClient, username, password, localIP, backupPath)
-	location := fmt.Sprintf("%s:%s", localIP, backupPath)
-	password = url.QueryEscape(password)
-	options := sms.NewBackupDatabaseOptionsSFTP(location, username, password)
-	options.SetSSLPrivateKeys(false).SetTimestamp(false).SetEvents(false)
-	log.Printf("Initiate backup: %v -> %s", smsClient, localIP)
-	err := smsClient.BackupDatabase(options)
+// GetOutputColumns returns report columns from configuration.
+func GetOutputColumns() []certlist.Column {
+	columns, err := config.GetOutputColumns()
 	if err != nil {
-		Panic("backup database: %v", err)
+		Panic("%s: %v", config.OutputColumns, err)
 	}
+	return columns
 }
 
-func GetOutboundIP(address string) (net.IP, error) {
-	conn, err := net.Dial("tcp", address)
+// GetOptions returns certlist options from configuration.
+func GetOptions() certlist.Options {
+	exePath, err := os.Executable()
 	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-	localAddr := conn.LocalAddr().(*net.TCPAddr)
-	return localAddr.IP, nil
-}
-
-func RandStringBytesRmndr(n int) string {
-	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letterBytes[rand.Int63()%int64(len(letterBytes))]
-	}
-	return string(b)
-}
-
-func GetTempDir() string {
-	tempDir := viper.GetString(config.TempDir)
-	if tempDir == "" {
-		var err error
-		tempDir, err = os.MkdirTemp(viper.GetString(config.TempDir), "cl-*")
-		if err != nil {
-			Panic("TempDir: %v", err)
-		}
+		panic(err)
 	}
-	log.Printf("Temp folder: %s", tempDir)
-	return tempDir
-}
-
-func LogSize(backupPath string) {
-	info, err := os.Stat(backupPath)
-	if err != nil {
-		Panic("stat: %v", err)
+	return certlist.Options{
+		SMS: GetSMSOptions(),
+		SFTP: certlist.SFTPOptions{
+			UsernameLength: viper.GetInt(config.SFTPUsernameLength),
+			PasswordLength: viper.GetInt(config.SFTPPasswordLength),
+			Port:           viper.GetInt(config.SFTPPort),
+			Timeout:        viper.GetDuration(config.SFTPTimeout),
+		},
+		Transfer: certlist.TransferOptions{
+			Mode:       viper.GetString(config.TransferMode),
+			Location:   viper.GetString(config.TransferLocation),
+			Username:   viper.GetString(config.TransferUsername),
+			Password:   viper.GetString(config.TransferPassword),
+			Address:    viper.GetString(config.TransferAddress),
+			Dir:        viper.GetString(config.TransferDir),
+			KnownHosts: viper.GetString(config.TransferKnownHosts),
+		},
+		Cache: certlist.CacheOptions{
+			Dir:    viper.GetString(config.BackupCacheDir),
+			MaxAge: viper.GetDuration(config.BackupMaxAge),
+		},
+		Output: certlist.OutputOptions{
+			Filename:  viper.GetString(config.OutputFilename),
+			Strict:    viper.GetBool(config.OutputStrict),
+			Semicolon: viper.GetBool(config.OutputSemicolon),
+			NoTZ:      viper.GetBool(config.OutputNoTZ),
+			Layout:    viper.GetString(config.OutputLayout),
+			Format:    viper.GetString(config.OutputFormat),
+			Template:  viper.GetString(config.OutputTemplate),
+			Columns:   GetOutputColumns(),
+		},
+		Alert: certlist.AlertOptions{
+			CriticalDays: viper.GetInt(config.AlertCriticalDays),
+			WarningDays:  viper.GetInt(config.AlertWarningDays),
+			OnlyStatus:   viper.GetStringSlice(config.OnlyStatus),
+		},
+		Policy:          GetPolicyOptions(),
+		Concurrency:     viper.GetInt(config.BackupConcurrency),
+		DatabaseBackend: viper.GetString(config.DatabaseBackend),
+		Database: backend.Options{
+			MariaDBZip:   filepath.Join(filepath.Dir(exePath), viper.GetString(config.MariaDB)),
+			Port:         viper.GetInt(config.DatabasePort),
+			DSN:          viper.GetString(config.DatabaseDSN),
+			DatabaseName: viper.GetString(config.DatabaseName),
+		},
+		TempDir:    viper.GetString(config.TempDir),
+		Backup:     viper.GetString(config.Backup),
+		Passphrase: viper.GetString(config.BackupPassphrase),
+		NoCleanup:  viper.GetBool(config.NoCleanup),
 	}
-	log.Printf("Got backup file: %s", formatFileSize(info.Size()))
 }
 
 func main() {
@@ -127,108 +125,16 @@ func main() {
 		log.Println("Exiting")
 	}()
 	config.Configure()
-	tempDir := GetTempDir()
-	if !viper.GetBool(config.NoCleanup) {
-		defer func() {
-			log.Printf("Remove temporary folder %s", tempDir)
-			err := os.RemoveAll(tempDir)
-			if err != nil {
-				log.Print(err)
-			}
-		}()
-	}
-	backupName := GetBackupFileName()
-	backupPath := filepath.Join(tempDir, backupName)
-	if viper.GetString(config.Backup) != "" {
-		backupPath = viper.GetString(config.Backup)
-	} else {
-		localIP := GetLocalAddress()
-		log.Printf("Run local sFTP server")
-		port := 22
-		username := RandStringBytesRmndr(viper.GetInt(config.SFTPUsernameLength))
-		password := RandStringBytesRmndr(viper.GetInt(config.SFTPPasswordLength))
-		go secureftp.Run(username, password, localIP, port)
-		smsClient := GetSMS()
-		log.Printf("Run backup")
-		RunBackup(smsClient, username, password, localIP, backupPath)
-	}
-	LogSize(backupPath)
-	exePath, err := os.Executable()
-	if err != nil {
-		panic(err)
-	}
-	mariaDistib := filepath.Join(filepath.Dir(exePath), viper.GetString(config.MariaDB))
-	mariaDB := maria.NewDB(mariaDistib, tempDir)
-	log.Print("Extract MariaDB")
-	if err := mariaDB.Extract(); err != nil {
-		Panic("extract: %v", err)
-	}
-	log.Print("Initialize MariaDB")
-	if err := mariaDB.Init(); err != nil {
-		Panic("initialize: %v", err)
-	}
-	log.Print("Start MariaDB")
-	if err := mariaDB.Start(); err != nil {
-		Panic("start: %v", err)
-	}
-	defer func() {
-		log.Print("Stop MariaDB")
-		err := mariaDB.Stop()
-		if err != nil {
-			log.Print(err)
-		}
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func() {
+		<-ctx.Done()
+		// Second signal terminates certlist immediately
+		stopSignals()
 	}()
-	time.Sleep(2 * time.Second)
-	log.Print("Extract dump")
-	dumpFile, err := smsbackup.ExtractDump(backupPath)
-	if err != nil {
-		Panic("ExtractDump: %v", err)
-	}
-	log.Print("Connect to the MariaDB")
-	db, err := mariaDB.Open("")
-	if err != nil {
-		Panic("connect: %v", err)
-	}
-	log.Print("Ping MariaDB")
-	if err := db.Ping(); err != nil {
-		Panic("Failed to ping database: %v", err)
-	}
-	log.Print("Create database")
-	if err := maria.CreateDatabase(db); err != nil {
-		Panic("create Database: %v", err)
-	}
-	log.Print("Close MariaDB connection")
-	if err := db.Close(); err != nil {
-		Panic("close database: %v", err)
-	}
-	log.Print("Populate database")
-	if err = mariaDB.Populate(dumpFile, maria.DatabaseName); err != nil {
-		Panic("populate database: %v", err)
-	}
-	log.Printf("Connect to database %s", maria.DatabaseName)
-	db, err = mariaDB.Open(maria.DatabaseName)
-	if err != nil {
-		Panic("connect to %s: %v", maria.DatabaseName, err)
-	}
-	log.Print("Generate report")
-	report, err := smsbackup.GenerateReport_(db)
-	if err != nil {
-		Panic("GenerateReport: %v", err)
-	}
-	log.Print("Write report")
-	strict := viper.GetBool(config.OutputStrict)
-	semicolon := viper.GetBool(config.OutputSemicolon)
-	if err := SaveCSV(viper.GetString(config.OutputFilename), report, strict, semicolon); err != nil {
-		Panic("SaveCSV: %v", err)
-	}
-	if !viper.GetBool(config.NoCleanup) {
-		log.Print("Delete database")
-		err = maria.DropDatabase(db)
-		if err != nil {
-			log.Print(err)
-		}
+	if _, err := certlist.Run(ctx, GetOptions()); err != nil {
+		Panic("%v", err)
 	}
-	log.Printf("Report saved to %s", viper.GetString(config.OutputFilename))
 }
 
 func Panic(format string, v ...any) {
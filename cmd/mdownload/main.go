@@ -3,21 +3,45 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
 
 	"github.com/tidwall/gjson"
 )
 
 const (
-	version        = "11.7"
-	outputFilename = "mariadb-latest.zip"
+	version = "11.7"
 )
 
+// Package describes MariaDB distributive for particular platform.
+type Package struct {
+	OS             string
+	PackageType    string
+	NameSuffix     string
+	OutputFilename string
+}
+
+var packages = map[string]Package{
+	"windows": {
+		OS:             "Windows",
+		PackageType:    "ZIP file",
+		NameSuffix:     "winx64.zip",
+		OutputFilename: "mariadb-latest.zip",
+	},
+	"linux": {
+		OS:             "Linux",
+		PackageType:    "gzipped tar file",
+		NameSuffix:     "linux-systemd-x86_64.tar.gz",
+		OutputFilename: "mariadb-latest.tar.gz",
+	},
+}
+
 type Download struct {
 	DownloadURL string `json:"download_url"`
 	Platform    string `json:"platform"`
@@ -30,6 +54,13 @@ type Release struct {
 }
 
 func main() {
+	targetOS := flag.String("os", runtime.GOOS, "Target OS: windows or linux")
+	flag.Parse()
+	pkg, ok := packages[*targetOS]
+	if !ok {
+		log.Fatalf("Unsupported OS: %s", *targetOS)
+	}
+	outputFilename := pkg.OutputFilename
 	log.Println("Fetching latest release info from MariaDB API...")
 	apiURL := fmt.Sprintf("https://downloads.mariadb.org/rest-api/mariadb/%s/latest/", version)
 	res, err := http.Get(apiURL)
@@ -53,16 +84,20 @@ func main() {
 	downloadURL := ""
 	sha256hash := ""
 	files.ForEach(func(_, file gjson.Result) bool {
-		if file.Get("os").String() != "Windows" {
+		if file.Get("os").String() != pkg.OS {
 			return true
 		}
 		if file.Get("cpu").String() != "x86_64" {
 			return true
 		}
-		if file.Get("package_type").String() != "ZIP file" {
+		if file.Get("package_type").String() != pkg.PackageType {
+			return true
+		}
+		fileName := file.Get("file_name").String()
+		if strings.Contains(fileName, "debugsymbols") {
 			return true
 		}
-		if strings.Contains(file.Get("file_name").String(), "debugsymbols") {
+		if !strings.HasSuffix(fileName, pkg.NameSuffix) {
 			return true
 		}
 		downloadURL = file.Get("file_download_url").String()
@@ -72,7 +107,7 @@ func main() {
 	if downloadURL == "" {
 		log.Fatal("No suitable download URL found.")
 	}
-	fmt.Printf("Downloading MariaDB ZIP from: %s\n", downloadURL)
+	fmt.Printf("Downloading MariaDB from: %s\n", downloadURL)
 
 	resp, err := http.Get(downloadURL)
 	if err != nil {
@@ -0,0 +1,70 @@
+// Package backend provides databases to load SMS dump into.
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Backend is a database SMS dump is loaded to.
+type Backend interface {
+	// Prepare makes database ready to start (extract, initialize, etc.)
+	Prepare(ctx context.Context) error
+	// Start runs database. Started processes are killed when ctx is done
+	Start(ctx context.Context) error
+	// Load populates database with dump
+	Load(ctx context.Context, dump io.Reader) error
+	// Open returns connection to populated database
+	Open() (*sql.DB, error)
+	// Stop removes loaded data and stops database. It should be called
+	// even if ctx passed to other methods is canceled
+	Stop() error
+}
+
+const (
+	KindMemory    = "memory"
+	KindPortable  = "portable"
+	KindInstalled = "installed"
+	KindServer    = "server"
+)
+
+var ErrUnknownBackend = errors.New("unknown database backend")
+
+// Options for all kinds of backends. Each kind uses only part of them.
+type Options struct {
+	// TempDir is folder for database files (portable, installed)
+	TempDir string
+	// MariaDBZip is portable MariaDB distributive (portable)
+	MariaDBZip string
+	// Port for MariaDB to listen on, 0 to choose free port (portable, installed)
+	Port int
+	// DSN of existing MySQL/MariaDB server (server)
+	DSN string
+	// DatabaseName to create on existing server (server)
+	DatabaseName string
+	// Keep loaded data on Stop
+	Keep bool
+}
+
+// New returns backend of given kind.
+func New(kind string, options Options) (Backend, error) {
+	switch kind {
+	case KindMemory, "":
+		return NewMemory(), nil
+	case KindPortable:
+		return NewPortable(options.MariaDBZip, options.TempDir, options.Port, options.Keep), nil
+	case KindInstalled:
+		return NewInstalled(options.TempDir, options.Port, options.Keep), nil
+	case KindServer:
+		server, err := NewServer(options.DSN, options.DatabaseName, options.Keep)
+		if err != nil {
+			return nil, err
+		}
+		return server, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", kind, ErrUnknownBackend)
+	}
+}
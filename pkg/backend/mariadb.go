@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mpkondrashin/certlist/pkg/maria"
+)
+
+// MariaDB runs private mariadbd instance either from portable
+// distributive or installed in the system.
+type MariaDB struct {
+	db        *maria.DB
+	installed bool
+	keep      bool
+	loaded    bool
+}
+
+var _ Backend = &MariaDB{}
+
+// NewPortable returns backend that uses portable MariaDB ZIP.
+func NewPortable(distribPath, tempFolder string, port int, keep bool) *MariaDB {
+	return &MariaDB{
+		db:   maria.NewDB(distribPath, tempFolder).SetPort(port),
+		keep: keep,
+	}
+}
+
+// NewInstalled returns backend that uses MariaDB binaries found in PATH.
+func NewInstalled(tempFolder string, port int, keep bool) *MariaDB {
+	return &MariaDB{
+		db:        maria.NewDB("", tempFolder).SetPort(port),
+		installed: true,
+		keep:      keep,
+	}
+}
+
+func (m *MariaDB) Prepare(ctx context.Context) error {
+	if m.installed {
+		if err := m.db.Locate(); err != nil {
+			return fmt.Errorf("locate: %w", err)
+		}
+	} else {
+		if err := m.db.Extract(); err != nil {
+			return fmt.Errorf("extract: %w", err)
+		}
+	}
+	if err := m.db.Init(ctx); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	return nil
+}
+
+func (m *MariaDB) Start(ctx context.Context) error {
+	if err := m.db.Start(ctx); err != nil {
+		return err
+	}
+	return m.db.WaitReady(ctx, maria.ReadyTimeout)
+}
+
+func (m *MariaDB) Load(ctx context.Context, dump io.Reader) error {
+	db, err := m.db.Open("")
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	if err := maria.CreateDatabase(db); err != nil {
+		return fmt.Errorf("create database: %w", err)
+	}
+	m.loaded = true
+	return m.db.Populate(ctx, dump, maria.DatabaseName)
+}
+
+func (m *MariaDB) Open() (*sql.DB, error) {
+	return m.db.Open(maria.DatabaseName)
+}
+
+// Stop drops loaded database unless it should be kept and stops mariadbd.
+// mariadbd is stopped even if drop fails.
+func (m *MariaDB) Stop() error {
+	var dropErr error
+	if m.loaded && !m.keep {
+		dropErr = m.drop()
+	}
+	return errors.Join(dropErr, m.db.Stop())
+}
+
+func (m *MariaDB) drop() error {
+	db, err := m.db.Open("")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	m.loaded = false
+	return maria.DropDatabase(db)
+}
@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+
+	"github.com/mpkondrashin/certlist/pkg/dumpreader"
+)
+
+// Memory reads dump into memory using pkg/dumpreader.
+type Memory struct {
+	dump *dumpreader.Dump
+}
+
+var _ Backend = &Memory{}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Prepare(context.Context) error {
+	return nil
+}
+
+func (m *Memory) Start(context.Context) error {
+	return nil
+}
+
+func (m *Memory) Load(ctx context.Context, dump io.Reader) (err error) {
+	m.dump, err = dumpreader.Read(&contextReader{ctx: ctx, r: dump})
+	return
+}
+
+// contextReader stops reading when context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+var ErrNotLoaded = errors.New("dump is not loaded")
+
+func (m *Memory) Open() (*sql.DB, error) {
+	if m.dump == nil {
+		return nil, ErrNotLoaded
+	}
+	return m.dump.DB(), nil
+}
+
+func (m *Memory) Stop() error {
+	m.dump = nil
+	return nil
+}
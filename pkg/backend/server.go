@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/mpkondrashin/certlist/pkg/dumpreader"
+)
+
+// DefaultServerDatabaseName is a database created on existing server
+// if no other name is provided.
+const DefaultServerDatabaseName = "certlist"
+
+// Server loads dump into existing MySQL/MariaDB server. Dump statements
+// are executed one by one, so no mysql client is required.
+type Server struct {
+	dsn          string
+	databaseName string
+	keep         bool
+	loaded       bool
+}
+
+var _ Backend = &Server{}
+
+var ErrDatabaseName = errors.New("database name may contain only letters, digits and underscores")
+
+var databaseNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// NewServer returns backend for server available by given DSN,
+// e.g. "user:password@tcp(host:3306)/". Database with given name
+// is created on the server and should not exist beforehand.
+func NewServer(dsn, databaseName string, keep bool) (*Server, error) {
+	if databaseName == "" {
+		databaseName = DefaultServerDatabaseName
+	}
+	if !databaseNameRegexp.MatchString(databaseName) {
+		return nil, fmt.Errorf("%w: %q", ErrDatabaseName, databaseName)
+	}
+	return &Server{
+		dsn:          dsn,
+		databaseName: databaseName,
+		keep:         keep,
+	}, nil
+}
+
+func (s *Server) Prepare(context.Context) error {
+	_, err := s.config("")
+	return err
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	db, err := s.open("")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
+
+func (s *Server) Load(ctx context.Context, dump io.Reader) error {
+	db, err := s.open("")
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE `%s`", s.databaseName))
+	db.Close()
+	if err != nil {
+		return fmt.Errorf("create database: %w", err)
+	}
+	s.loaded = true
+	db, err = s.open(s.databaseName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// LOCK TABLES requires all statements to be run on the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	for stmt, err := range dumpreader.Statements(dump) {
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%w: %v", ErrLoad, err)
+		}
+	}
+	return nil
+}
+
+var ErrLoad = errors.New("failed to load dump")
+
+func (s *Server) Open() (*sql.DB, error) {
+	return s.open(s.databaseName)
+}
+
+func (s *Server) Stop() error {
+	if !s.loaded || s.keep {
+		return nil
+	}
+	db, err := s.open("")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	s.loaded = false
+	_, err = db.Exec(fmt.Sprintf("DROP DATABASE `%s`", s.databaseName))
+	return err
+}
+
+func (s *Server) config(databaseName string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(s.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("DSN: %w", err)
+	}
+	cfg.DBName = databaseName
+	return cfg, nil
+}
+
+func (s *Server) open(databaseName string) (*sql.DB, error) {
+	cfg, err := s.config(databaseName)
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open("mysql", cfg.FormatDSN())
+}
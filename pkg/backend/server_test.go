@@ -0,0 +1,19 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewServer(t *testing.T) {
+	for _, name := range []string{"", "certlist", "certlist_2", "SMS1"} {
+		if _, err := NewServer("user:password@tcp(localhost:3306)/", name, false); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"cert-list", "certlist`; DROP DATABASE mysql; --", "cert list", "база"} {
+		if _, err := NewServer("user:password@tcp(localhost:3306)/", name, false); !errors.Is(err, ErrDatabaseName) {
+			t.Errorf("%q: expected %v, got %v", name, ErrDatabaseName, err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package cache keeps SMS backups to reuse them instead of running new backup.
+//
+// Each backup is stored with metadata file (same name with .json extension)
+// holding SMS address, timestamp, SHA-256 and size of the backup.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	backupExt   = ".gz"
+	metadataExt = ".json"
+)
+
+var (
+	ErrCorrupted   = errors.New("cached backup is corrupted")
+	ErrUnknownFile = errors.New("metadata refers to unexpected file")
+)
+
+// Entry is metadata of the cached backup.
+type Entry struct {
+	SMS       string    `json:"sms"`
+	Timestamp time.Time `json:"timestamp"`
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	// File is a name of the backup file in cache folder
+	File string `json:"file"`
+}
+
+// Age of the backup.
+func (e *Entry) Age() time.Duration {
+	return time.Since(e.Timestamp)
+}
+
+// Cache of the SMS backups.
+type Cache struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// New returns cache in dir. Backups older than maxAge are not used and pruned.
+func New(dir string, maxAge time.Duration) *Cache {
+	return &Cache{dir: dir, maxAge: maxAge}
+}
+
+// DefaultDir returns certlist folder in user cache folder.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "certlist"), nil
+}
+
+var unsafeRegexp = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// baseName returns file name without extension for backup of sms made at t.
+func baseName(sms string, t time.Time) string {
+	return unsafeRegexp.ReplaceAllString(sms, "_") + "-" + t.UTC().Format("20060102T150405.000000000")
+}
+
+// Entries returns metadata of all cached backups.
+func (c *Cache) Entries() ([]*Entry, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*"+metadataExt))
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for _, file := range files {
+		entry, err := readEntry(file)
+		if err != nil {
+			log.Printf("Cache: %s: %v", file, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readEntry reads metadata file. Backup file name must be the same as the
+// name of the metadata file, so tampered metadata can not point outside of
+// the cache folder.
+func readEntry(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	expected := strings.TrimSuffix(filepath.Base(path), metadataExt) + backupExt
+	if entry.File != expected {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFile, entry.File)
+	}
+	return &entry, nil
+}
+
+// Get returns path of the newest backup of sms not older than maxAge.
+// Backups with wrong size or checksum are removed.
+func (c *Cache) Get(sms string) (string, *Entry, bool) {
+	entries, err := c.Entries()
+	if err != nil {
+		log.Printf("Cache: %v", err)
+		return "", nil, false
+	}
+	var newest *Entry
+	for _, entry := range entries {
+		if entry.SMS != sms || entry.Age() > c.maxAge {
+			continue
+		}
+		if newest == nil || entry.Timestamp.After(newest.Timestamp) {
+			newest = entry
+		}
+	}
+	if newest == nil {
+		return "", nil, false
+	}
+	path := filepath.Join(c.dir, newest.File)
+	if err := verify(path, newest); err != nil {
+		log.Printf("Cache: %s: %v", path, err)
+		c.remove(newest)
+		return "", nil, false
+	}
+	return path, newest, true
+}
+
+// verify checks size and checksum of the backup.
+func verify(path string, entry *Entry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if size != entry.Size || hex.EncodeToString(h.Sum(nil)) != entry.SHA256 {
+		return ErrCorrupted
+	}
+	return nil
+}
+
+// Put copies backup of sms to cache.
+func (c *Cache) Put(sms, backupPath string) (*Entry, error) {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		SMS:       sms,
+		Timestamp: time.Now(),
+	}
+	base := baseName(sms, entry.Timestamp)
+	entry.File = base + backupExt
+	if err := c.copy(backupPath, entry); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, base+metadataExt), data, 0600); err != nil {
+		os.Remove(filepath.Join(c.dir, entry.File))
+		return nil, err
+	}
+	return entry, nil
+}
+
+// copy copies backup to cache and sets its size and checksum to entry.
+func (c *Cache) copy(backupPath string, entry *Entry) error {
+	src, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	target := filepath.Join(c.dir, entry.File)
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	entry.Size, err = io.Copy(io.MultiWriter(dst, h), src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return fmt.Errorf("copy to cache: %w", err)
+	}
+	entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+func (c *Cache) remove(entry *Entry) {
+	base := strings.TrimSuffix(entry.File, backupExt)
+	for _, name := range []string{entry.File, base + metadataExt} {
+		if err := os.Remove(filepath.Join(c.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Cache: %v", err)
+		}
+	}
+}
+
+// Prune removes backups older than maxAge and backups without metadata.
+func (c *Cache) Prune() error {
+	entries, err := c.Entries()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Age() > c.maxAge {
+			log.Printf("Cache: remove %s backup of %s", entry.Timestamp.Format(time.DateTime), entry.SMS)
+			c.remove(entry)
+			continue
+		}
+		known[entry.File] = true
+	}
+	files, err := filepath.Glob(filepath.Join(c.dir, "*"+backupExt))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if known[filepath.Base(file)] {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			log.Printf("Cache: %v", err)
+		}
+	}
+	return nil
+}
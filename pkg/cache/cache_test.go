@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	backupPath := filepath.Join(t.TempDir(), "backup.gz")
+	if err := os.WriteFile(backupPath, []byte("backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New(filepath.Join(dir, "cache"), time.Hour)
+	if _, _, ok := c.Get("1.2.3.4"); ok {
+		t.Fatal("empty cache returned backup")
+	}
+	entry, err := c.Put("1.2.3.4", backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Size != 6 {
+		t.Errorf("expected size 6, got %d", entry.Size)
+	}
+	path, got, ok := c.Get("1.2.3.4")
+	if !ok {
+		t.Fatal("cached backup not found")
+	}
+	if got.SHA256 != entry.SHA256 {
+		t.Errorf("expected %s, got %s", entry.SHA256, got.SHA256)
+	}
+	if _, _, ok := c.Get("5.6.7.8"); ok {
+		t.Error("backup of other SMS returned")
+	}
+	if err := os.WriteFile(path, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := c.Get("1.2.3.4"); ok {
+		t.Error("corrupted backup returned")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("corrupted backup is not removed")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "backup.gz")
+	if err := os.WriteFile(backupPath, []byte("backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New(filepath.Join(dir, "cache"), time.Hour)
+	if _, err := c.Put("1.2.3.4", backupPath); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(dir, "cache", "orphan.gz")
+	if err := os.WriteFile(orphan, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("backup without metadata is not removed")
+	}
+	if _, _, ok := c.Get("1.2.3.4"); !ok {
+		t.Error("fresh backup is pruned")
+	}
+	c.maxAge = -time.Second
+	if err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("stale backups are not pruned: %d", len(entries))
+	}
+}
+
+func TestTamperedEntry(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside.gz")
+	if err := os.WriteFile(outside, []byte("backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := New(filepath.Join(dir, "cache"), time.Hour)
+	entry, err := c.Put("1.2.3.4", outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := strings.TrimSuffix(entry.File, backupExt)
+	for _, file := range []string{"../outside.gz", "other.gz", base + ".txt"} {
+		data := fmt.Sprintf(`{"sms":"1.2.3.4","timestamp":%q,"sha256":%q,"size":6,"file":%q}`,
+			entry.Timestamp.Format(time.RFC3339Nano), entry.SHA256, file)
+		if err := os.WriteFile(filepath.Join(dir, "cache", base+metadataExt), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readEntry(filepath.Join(dir, "cache", base+metadataExt)); !errors.Is(err, ErrUnknownFile) {
+			t.Errorf("%s: expected %v, got %v", file, ErrUnknownFile, err)
+		}
+		if _, _, ok := c.Get("1.2.3.4"); ok {
+			t.Errorf("%s: tampered entry returned", file)
+		}
+		c.maxAge = -time.Second
+		if err := c.Prune(); err != nil {
+			t.Fatal(err)
+		}
+		c.maxAge = time.Hour
+		if _, err := os.Stat(outside); err != nil {
+			t.Errorf("%s: file outside of cache is removed", file)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package certlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mpkondrashin/certalert/pkg/sms"
+
+	"github.com/mpkondrashin/certlist/pkg/cache"
+	"github.com/mpkondrashin/certlist/pkg/receiver"
+)
+
+func GetSMS(options SMSOptions) *sms.SMS {
+	auth := sms.NewAPIKeyAuthorization(options.APIKey)
+	smsClient := sms.New("https://"+options.Address, auth)
+	return smsClient.SetInsecureSkipVerify(options.IgnoreTLSErrors)
+}
+
+func GetLocalAddress(smsAddress string) (string, error) {
+	log.Printf("Dial SMS (%s)", smsAddress)
+	localIP, err := GetOutboundIP(smsAddress + ":443")
+	if err != nil {
+		return "", err
+	}
+	log.Printf("SMS connection succeeded")
+	log.Printf("Local address %v", localIP)
+	return localIP.String(), nil
+}
+
+func GetBackupFileName() (string, error) {
+	backupBaseName, err := receiver.RandomString(16)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(backupBaseName) + ".gz", nil
+}
+
+func FilterBackupPath(backupPath string) (string, error) {
+	if runtime.GOOS != "windows" {
+		return backupPath, nil
+	}
+	path, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	currentDrive := path[:2]
+	if !strings.HasPrefix(backupPath, currentDrive) {
+		return "", fmt.Errorf("TEMP is on %s drive and not on current drive: %s", backupPath[:2], currentDrive)
+	}
+	backupPath = backupPath[2:]
+	return strings.ReplaceAll(backupPath, "\\", "/"), nil
+}
+
+// RunBackup initiates SMS backup to location of given kind (see Location*
+// constants) and waits for its completion. If ctx is done, RunBackup returns
+// immediately, though SMS may continue the backup.
+func RunBackup(ctx context.Context, smsClient *sms.SMS, kind, location, username, password string) error {
+	password = url.QueryEscape(password)
+	options := sms.NewBackupDatabaseOptionsSFTP(location, username, password)
+	switch kind {
+	case LocationSMB:
+		options = sms.NewBackupDatabaseOptionsSMB(location, username, password)
+	case LocationLocal:
+		options = sms.NewBackupDatabaseOptionsLocal(location)
+	}
+	options.SetSSLPrivateKeys(false).SetTimestamp(false).SetEvents(false)
+	log.Printf("Initiate backup: %v -> %s", smsClient, location)
+	result := make(chan error, 1)
+	go func() {
+		result <- smsClient.BackupDatabase(options)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("backup database: %w", err)
+		}
+		return nil
+	}
+}
+
+func GetOutboundIP(address string) (net.IP, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	localAddr := conn.LocalAddr().(*net.TCPAddr)
+	return localAddr.IP, nil
+}
+
+// transfer is a backup expected from single SMS.
+type transfer struct {
+	// name of the backup file
+	name string
+	// backupPath is a local path of the backup
+	backupPath string
+	// location SMS stores backup to, its kind and credentials
+	kind     string
+	location string
+	username string
+	password string
+	// localIP of the receiver, receiver itself and upload it expects (push mode)
+	localIP  string
+	receiver *receiver.Receiver
+	upload   *receiver.Upload
+	// cached is true if backupPath is backup from cache
+	cached bool
+}
+
+// prepareTransfers fills transfers for servers not having them yet. In push
+// mode, it starts sFTP receivers (one per local address) expecting backups
+// from these servers. Errors are stored to errs per server. Returned function
+// stops receivers.
+func (r *runner) prepareTransfers(ctx context.Context, servers []SMSOptions, folders []string, transfers []*transfer, errs []error) func() {
+	receivers := make(map[string]*receiver.Receiver)
+	closeReceivers := func() {
+		for _, rcv := range receivers {
+			rcv.Close()
+		}
+	}
+	for i, server := range servers {
+		if errs[i] != nil || transfers[i] != nil {
+			continue
+		}
+		var t *transfer
+		var err error
+		if r.options.Transfer.Mode == TransferPull {
+			t, err = r.preparePull(folders[i])
+		} else {
+			t, err = r.preparePush(server, folders[i], receivers)
+		}
+		if err != nil {
+			errs[i] = stageError(ErrBackup, fmt.Errorf("%s: %w", server.Address, err))
+			continue
+		}
+		transfers[i] = t
+	}
+	for localIP, rcv := range receivers {
+		if err := rcv.Start(ctx); err != nil {
+			for i, t := range transfers {
+				if t != nil && t.upload != nil && t.localIP == localIP {
+					errs[i] = stageError(ErrTransfer, fmt.Errorf("%s: %w", servers[i].Address, err))
+				}
+			}
+		}
+	}
+	return closeReceivers
+}
+
+func (r *runner) preparePull(folder string) (*transfer, error) {
+	backupName, err := GetBackupFileName()
+	if err != nil {
+		return nil, err
+	}
+	l, err := parseLocation(r.options.Transfer.Location)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer{
+		name:       backupName,
+		backupPath: filepath.Join(folder, backupName),
+		kind:       l.kind,
+		location:   l.backupLocation(backupName),
+		username:   r.options.Transfer.Username,
+		password:   r.options.Transfer.Password,
+	}, nil
+}
+
+func (r *runner) preparePush(server SMSOptions, folder string, receivers map[string]*receiver.Receiver) (*transfer, error) {
+	localIP, err := GetLocalAddress(server.Address)
+	if err != nil {
+		return nil, err
+	}
+	backupName, err := GetBackupFileName()
+	if err != nil {
+		return nil, err
+	}
+	backupPath := filepath.Join(folder, backupName)
+	name, err := FilterBackupPath(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	rcv, ok := receivers[localIP]
+	if !ok {
+		rcv, err = receiver.New(receiver.Options{
+			Address:        localIP,
+			Port:           r.options.SFTP.Port,
+			Timeout:        r.options.SFTP.Timeout,
+			UsernameLength: r.options.SFTP.UsernameLength,
+			PasswordLength: r.options.SFTP.PasswordLength,
+		})
+		if err != nil {
+			return nil, err
+		}
+		receivers[localIP] = rcv
+	}
+	upload, err := rcv.Expect(server.Address, name, backupPath)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer{
+		name:       backupName,
+		backupPath: backupPath,
+		location:   localIP + ":" + name,
+		username:   rcv.Username(),
+		password:   rcv.Password(),
+		kind:       LocationSFTP,
+		localIP:    localIP,
+		receiver:   rcv,
+		upload:     upload,
+	}, nil
+}
+
+// backup gets backup from SMS and returns its path.
+func (r *runner) backup(ctx context.Context, server SMSOptions, t *transfer) (string, error) {
+	if r.options.Backup != "" {
+		return r.options.Backup, nil
+	}
+	if t.cached {
+		return t.backupPath, nil
+	}
+	smsClient := GetSMS(server)
+	if t.upload != nil {
+		// Upload timeout starts now and not when receiver is started, as
+		// this backup could wait for others due to concurrency limit
+		t.receiver.Arm(t.upload)
+	}
+	log.Printf("Run backup of %s", server.Address)
+	if err := RunBackup(ctx, smsClient, t.kind, t.location, t.username, t.password); err != nil {
+		return "", stageError(ErrBackup, fmt.Errorf("%s: %w", server.Address, err))
+	}
+	if t.upload == nil {
+		if err := Pull(ctx, r.options.Transfer, server.Address, t.name, t.backupPath, r.options.NoCleanup); err != nil {
+			return "", stageError(ErrTransfer, fmt.Errorf("%s: %w", server.Address, err))
+		}
+		r.store(server, t.backupPath)
+		return t.backupPath, nil
+	}
+	select {
+	case <-ctx.Done():
+		return "", stageError(ErrTransfer, ctx.Err())
+	case <-t.upload.Done():
+	}
+	if err := t.upload.Err(); err != nil {
+		return "", stageError(ErrTransfer, fmt.Errorf("%s: %w", server.Address, err))
+	}
+	r.store(server, t.backupPath)
+	return t.backupPath, nil
+}
+
+// openCache returns cache of backups with stale entries pruned. It returns
+// nil if cache can not be used.
+func openCache(options CacheOptions) *cache.Cache {
+	dir := options.Dir
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		if err != nil {
+			log.Printf("Backup cache is disabled: %v", err)
+			return nil
+		}
+	}
+	log.Printf("Backup cache: %s", dir)
+	c := cache.New(dir, options.MaxAge)
+	if err := c.Prune(); err != nil {
+		log.Printf("Prune backup cache: %v", err)
+	}
+	return c
+}
+
+// cachedTransfers fills transfers for servers having fresh backup in cache.
+func (r *runner) cachedTransfers(servers []SMSOptions, transfers []*transfer) {
+	if r.cache == nil {
+		return
+	}
+	for i, server := range servers {
+		backupPath, entry, ok := r.cache.Get(server.Address)
+		if !ok {
+			continue
+		}
+		log.Printf("Use cached backup of %s made %s ago", server.Address, entry.Age().Round(time.Second))
+		transfers[i] = &transfer{
+			name:       entry.File,
+			backupPath: backupPath,
+			cached:     true,
+		}
+	}
+}
+
+// store puts backup to cache. Errors are only logged.
+func (r *runner) store(server SMSOptions, backupPath string) {
+	if r.cache == nil {
+		return
+	}
+	if _, err := r.cache.Put(server.Address, backupPath); err != nil {
+		log.Printf("Cache backup of %s: %v", server.Address, err)
+	}
+}
+
+func LogSize(backupPath string) error {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("Got backup file: %s", formatFileSize(info.Size()))
+	return nil
+}
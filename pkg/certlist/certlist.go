@@ -0,0 +1,274 @@
+// Package certlist runs the whole pipeline: gets backup from SMS, loads it
+// into database and generates report on certificates.
+package certlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/backend"
+	"github.com/mpkondrashin/certlist/pkg/cache"
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+// SMSOptions are parameters to connect to Tipping Point SMS.
+type SMSOptions struct {
+	Address         string
+	APIKey          string
+	IgnoreTLSErrors bool
+}
+
+// SFTPOptions are parameters of the local sFTP server SMS uploads backup to.
+type SFTPOptions struct {
+	UsernameLength int
+	PasswordLength int
+	// Port to listen on, receiver.DefaultPort if 0
+	Port int
+	// Timeout to wait for each backup counted from its start, receiver.DefaultTimeout if 0
+	Timeout time.Duration
+}
+
+// CacheOptions control reuse of SMS backups. Cache is disabled if MaxAge is 0.
+type CacheOptions struct {
+	// Dir to keep backups in. cache.DefaultDir() is used if empty
+	Dir string
+	// MaxAge of the backup to be reused
+	MaxAge time.Duration
+}
+
+// OutputOptions control report rendering. Report is not saved if Filename is empty.
+type OutputOptions struct {
+	Filename  string
+	Strict    bool
+	Semicolon bool
+	NoTZ      bool
+	// Layout of the report (see smsbackup.Layout* constants)
+	Layout string
+	// Format of the report (see Format* constants). Chosen by Filename
+	// extension if empty
+	Format string
+	// Template is a text/template file to render report with (see FormatTemplate)
+	Template string
+	// Columns of CSV report, HTML table and Excel Certificates sheet. All
+	// fields (see Strict) are written if empty. Must be empty for other
+	// formats
+	Columns []Column
+}
+
+// AlertOptions control expiry status of certificates.
+type AlertOptions struct {
+	// CriticalDays and WarningDays are thresholds of days to expiry
+	CriticalDays int
+	WarningDays  int
+	// OnlyStatus limits report to certificates with these statuses (see
+	// smsbackup.Status* constants). All certificates are reported if empty
+	OnlyStatus []string
+}
+
+// PolicyOptions control checks of certificates against policy rules.
+type PolicyOptions struct {
+	// Rules to check certificates against. No checks if empty
+	Rules []smsbackup.Rule
+	// Findings is a filename for findings report. Not saved if empty
+	Findings string
+}
+
+// Options of the certlist pipeline.
+type Options struct {
+	// SMS servers to get backups from
+	SMS      []SMSOptions
+	SFTP     SFTPOptions
+	Transfer TransferOptions
+	Cache    CacheOptions
+	Output   OutputOptions
+	Alert    AlertOptions
+	Policy   PolicyOptions
+	// Concurrency is a number of SMS servers processed in parallel
+	Concurrency int
+	// DatabaseBackend is a kind of backend (see pkg/backend)
+	DatabaseBackend string
+	// Database options. TempDir and Keep are set by Run
+	Database backend.Options
+	// TempDir is a folder to create temporary folder in. System default is used if empty
+	TempDir string
+	// Backup is existing SMS backup file to use instead of getting it from SMS.
+	// Only first SMS is used to label the report in this case
+	Backup string
+	// Passphrase of encrypted backups
+	Passphrase string
+	// NoCleanup keeps temporary files
+	NoCleanup bool
+}
+
+// Report is a result of the certlist pipeline.
+type Report struct {
+	Lines []smsbackup.ReportLine
+}
+
+// Run gets backups, loads them and generates combined report. Returned errors
+// are of *StageError type (joined if several SMS servers failed).
+func Run(ctx context.Context, options Options) (*Report, error) {
+	switch options.Transfer.Mode {
+	case "", TransferPush:
+	case TransferPull:
+		if _, err := parseLocation(options.Transfer.Location); err != nil && options.Backup == "" {
+			return nil, stageError(ErrTransfer, err)
+		}
+	default:
+		return nil, stageError(ErrTransfer, fmt.Errorf("%w: %s", ErrUnknownTransferMode, options.Transfer.Mode))
+	}
+	format, err := outputFormat(options.Output)
+	if err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	if err := checkColumns(format, options.Output); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	if _, err := outputColumns[smsbackup.ReportLine](options.Output.Columns, options.Output.Strict); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	if format == FormatTemplate {
+		if _, err := loadTemplate(options.Output.Template); err != nil {
+			return nil, stageError(ErrRender, err)
+		}
+	}
+	if err := smsbackup.CheckLayout(options.Output.Layout); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	if err := smsbackup.CheckStatuses(options.Alert.OnlyStatus); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	policy, err := smsbackup.NewPolicy(options.Policy.Rules)
+	if err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	tempDir, err := os.MkdirTemp(options.TempDir, "cl-*")
+	if err != nil {
+		return nil, stageError(ErrBackup, fmt.Errorf("TempDir: %w", err))
+	}
+	log.Printf("Temp folder: %s", tempDir)
+	if !options.NoCleanup {
+		defer func() {
+			log.Printf("Remove temporary folder %s", tempDir)
+			if err := os.RemoveAll(tempDir); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	servers := options.SMS
+	if options.Backup != "" && len(servers) > 1 {
+		servers = servers[:1]
+	}
+	if len(servers) == 0 {
+		servers = []SMSOptions{{}}
+	}
+	concurrency := max(options.Concurrency, 1)
+	r := &runner{options: options, policy: policy}
+	if options.Backup == "" && options.Cache.MaxAge > 0 {
+		r.cache = openCache(options.Cache)
+	}
+	results := make([][]smsbackup.ReportLine, len(servers))
+	errs := make([]error, len(servers))
+	folders := make([]string, len(servers))
+	for i := range servers {
+		folders[i] = filepath.Join(tempDir, fmt.Sprintf("sms%d", i+1))
+		if err := os.MkdirAll(folders[i], 0755); err != nil {
+			errs[i] = stageError(ErrBackup, err)
+		}
+	}
+	transfers := make([]*transfer, len(servers))
+	if options.Backup == "" {
+		r.cachedTransfers(servers, transfers)
+		closeReceivers := r.prepareTransfers(ctx, servers, folders, transfers, errs)
+		defer closeReceivers()
+	}
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		if errs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i], errs[i] = r.runSMS(ctx, i, len(servers), server, folders[i], transfers[i])
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	report := &Report{Lines: smsbackup.FilterStatus(slices.Concat(results...), options.Alert.OnlyStatus)}
+	if options.Output.Filename == "" {
+		return report, nil
+	}
+	log.Print("Write report")
+	if err := save(options.Output, report.Lines); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	log.Printf("Report saved to %s", options.Output.Filename)
+	if options.Policy.Findings == "" {
+		return report, nil
+	}
+	if err := SaveCSV(options.Policy.Findings, smsbackup.FindingsReport(report.Lines), true, options.Output.Semicolon); err != nil {
+		return nil, stageError(ErrRender, err)
+	}
+	log.Printf("Findings saved to %s", options.Policy.Findings)
+	return report, nil
+}
+
+// runner holds state shared by all SMS servers processed by Run.
+type runner struct {
+	options Options
+	// cache of backups, nil if disabled
+	cache *cache.Cache
+	// policy to check certificates against
+	policy *smsbackup.Policy
+}
+
+// runSMS generates report for single SMS.
+func (r *runner) runSMS(ctx context.Context, index, count int, server SMSOptions, tempDir string, t *transfer) ([]smsbackup.ReportLine, error) {
+	backupPath, err := r.backup(ctx, server, t)
+	if err != nil {
+		return nil, err
+	}
+	if err := LogSize(backupPath); err != nil {
+		return nil, stageError(ErrTransfer, err)
+	}
+	databaseOptions := r.options.Database
+	databaseOptions.TempDir = tempDir
+	databaseOptions.Keep = r.options.NoCleanup
+	if count > 1 && databaseOptions.DatabaseName != "" {
+		// Each SMS needs its own database on the shared server
+		databaseOptions.DatabaseName += fmt.Sprintf("_%d", index+1)
+	}
+	db, stop, err := load(ctx, r.options.DatabaseBackend, databaseOptions, backupPath, r.options.Passphrase)
+	if err != nil {
+		return nil, stageError(ErrLoad, fmt.Errorf("%s: %w", server.Address, err))
+	}
+	defer stop()
+	log.Printf("Generate report for %s", server.Address)
+	lines, err := smsbackup.GenerateReport_(ctx, db, smsbackup.ReportOptions{
+		NoTZ:         r.options.Output.NoTZ,
+		Layout:       r.options.Output.Layout,
+		CriticalDays: r.options.Alert.CriticalDays,
+		WarningDays:  r.options.Alert.WarningDays,
+		Policy:       r.policy,
+	})
+	if err != nil {
+		return nil, stageError(ErrQuery, fmt.Errorf("%s: %w", server.Address, err))
+	}
+	for i := range lines {
+		lines[i].SMS = server.Address
+	}
+	return lines, nil
+}
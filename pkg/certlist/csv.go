@@ -0,0 +1,207 @@
+package certlist
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// Column maps report field to output column.
+type Column struct {
+	// Field is a name of the struct field, its csv tag (e.g. "[ServerName]")
+	// or text/template executed with the line to get computed value (e.g.
+	// "{{.CertName}}@{{.SMS}}", see templateFuncs for helper functions)
+	Field string
+	// Header of the column. Field is used if empty
+	Header string
+}
+
+var ErrUnknownField = errors.New("unknown field")
+
+// skipField reports whether field is unexported or excluded from CSV by "-" tag
+func skipField(field reflect.StructField) bool {
+	return !field.IsExported() || field.Tag.Get("csv") == "-"
+}
+
+// column is an output column of the struct.
+type column struct {
+	header string
+	// field is a name of the struct field, empty for computed columns
+	field string
+	value func(v reflect.Value) (string, error)
+}
+
+// fieldColumn returns column of the struct field with given index.
+func fieldColumn(header, field string, index int) column {
+	return column{
+		header: header,
+		field:  field,
+		value: func(v reflect.Value) (string, error) {
+			return fmt.Sprintf("%v", v.Field(index).Interface()), nil
+		},
+	}
+}
+
+// templateColumn returns computed column. Template is executed with the
+// pointer to the struct, so its methods can be used as well.
+func templateColumn(header, text string) (column, error) {
+	tmpl, err := template.New(header).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return column{}, err
+	}
+	return column{
+		header: header,
+		value: func(v reflect.Value) (string, error) {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			var sb strings.Builder
+			if err := tmpl.Execute(&sb, p.Interface()); err != nil {
+				return "", err
+			}
+			return sb.String(), nil
+		},
+	}, nil
+}
+
+// getColumns returns struct fields to be written to CSV. If useTags is true,
+// only fields with csv tag are returned and tags are used as headers.
+func getColumns[T any](useTags bool) []column {
+	var t T
+	typ := reflect.TypeOf(t)
+	var columns []column
+
+	for i := range typ.NumField() {
+		if skipField(typ.Field(i)) {
+			continue
+		}
+		name := typ.Field(i).Name
+		if !useTags {
+			columns = append(columns, fieldColumn(name, name, i))
+			continue
+		}
+		header := typ.Field(i).Tag.Get("csv")
+		if header == "" {
+			continue
+		}
+		columns = append(columns, fieldColumn(header, name, i))
+	}
+	return columns
+}
+
+// mapColumns returns columns of struct T listed in mapping. Mapping fields
+// are matched against field names and csv tags.
+func mapColumns[T any](mapping []Column) ([]column, error) {
+	var t T
+	typ := reflect.TypeOf(t)
+	columns := make([]column, len(mapping))
+	for i, m := range mapping {
+		header := m.Header
+		if header == "" {
+			header = m.Field
+		}
+		if strings.Contains(m.Field, "{{") {
+			c, err := templateColumn(header, m.Field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", header, err)
+			}
+			columns[i] = c
+			continue
+		}
+		index := -1
+		for j := range typ.NumField() {
+			field := typ.Field(j)
+			if skipField(field) {
+				continue
+			}
+			if field.Name == m.Field || field.Tag.Get("csv") == m.Field {
+				index = j
+				break
+			}
+		}
+		if index == -1 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, m.Field)
+		}
+		columns[i] = fieldColumn(header, typ.Field(index).Name, index)
+	}
+	return columns, nil
+}
+
+// outputColumns returns columns listed in mapping or all columns (see
+// getColumns) if mapping is empty.
+func outputColumns[T any](mapping []Column, useTags bool) ([]column, error) {
+	if len(mapping) == 0 {
+		return getColumns[T](useTags), nil
+	}
+	return mapColumns[T](mapping)
+}
+
+// headers returns headers of the columns
+func headers(columns []column) []string {
+	result := make([]string, len(columns))
+	for i, c := range columns {
+		result[i] = c.header
+	}
+	return result
+}
+
+// row returns values of the columns of struct v
+func row(v any, columns []column) ([]string, error) {
+	val := reflect.ValueOf(v)
+	result := make([]string, len(columns))
+	for i, c := range columns {
+		value, err := c.value(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.header, err)
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func SaveCSV[T any](filename string, data []T, useTags bool, semicolon bool) error {
+	return SaveCSVColumns(filename, data, nil, useTags, semicolon)
+}
+
+// SaveCSVColumns writes data to CSV file using columns mapping. All columns
+// are written if mapping is empty.
+func SaveCSVColumns[T any](filename string, data []T, mapping []Column, useTags bool, semicolon bool) error {
+	columns, err := outputColumns[T](mapping, useTags)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if semicolon {
+		writer.Comma = ';'
+	}
+
+	if err := writer.Write(headers(columns)); err != nil {
+		return err
+	}
+
+	for _, line := range data {
+		values, err := row(line, columns)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
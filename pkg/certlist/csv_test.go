@@ -0,0 +1,114 @@
+package certlist
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type testLine struct {
+	Name    string `csv:"[Name]"`
+	Port    int
+	Aliases []string
+	Comment string `csv:"-"`
+	secret  string
+}
+
+func (l *testLine) Upper() string {
+	return strings.ToUpper(l.Name)
+}
+
+func TestMapColumns(t *testing.T) {
+	line := testLine{Name: "web", Port: 443, Aliases: []string{"www", "site"}, Comment: "comment", secret: "secret"}
+	testCases := []struct {
+		name    string
+		mapping []Column
+		headers []string
+		values  []string
+	}{
+		{"field name", []Column{{Field: "Port"}, {Field: "Name", Header: "Server"}}, []string{"Port", "Server"}, []string{"443", "web"}},
+		{"csv tag", []Column{{Field: "[Name]"}}, []string{"[Name]"}, []string{"web"}},
+		{"computed", []Column{{Field: "{{.Name}}:{{.Port}}", Header: "Address"}}, []string{"Address"}, []string{"web:443"}},
+		{"method", []Column{{Field: "{{.Upper}}"}}, []string{"{{.Upper}}"}, []string{"WEB"}},
+		{"helper function", []Column{{Field: `{{join "+" .Aliases}}`, Header: "Aliases"}}, []string{"Aliases"}, []string{"www+site"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			columns, err := mapColumns[testLine](tc.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := headers(columns); !slices.Equal(h, tc.headers) {
+				t.Errorf("expected headers %v, got %v", tc.headers, h)
+			}
+			values, err := row(line, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(values, tc.values) {
+				t.Errorf("expected %v, got %v", tc.values, values)
+			}
+		})
+	}
+	for _, field := range []string{"Missing", "Comment", "secret", "[Port]"} {
+		if _, err := mapColumns[testLine]([]Column{{Field: field}}); !errors.Is(err, ErrUnknownField) {
+			t.Errorf("%s: expected %v, got %v", field, ErrUnknownField, err)
+		}
+	}
+	if _, err := mapColumns[testLine]([]Column{{Field: "{{.Name"}}); err == nil {
+		t.Error("expected template parse error")
+	}
+	columns, err := mapColumns[testLine]([]Column{{Field: "{{.Missing}}", Header: "Missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := row(line, columns); err == nil || !strings.HasPrefix(err.Error(), "Missing: ") {
+		t.Errorf("expected template execution error, got %v", err)
+	}
+}
+
+func TestSaveCSVColumns(t *testing.T) {
+	data := []testLine{{Name: "web", Port: 443}, {Name: "mail;smtp", Port: 465}}
+	filename := filepath.Join(t.TempDir(), "report.csv")
+	mapping := []Column{{Field: "[Name]", Header: "Server"}, {Field: "{{.Port}}/tcp", Header: "Port"}}
+	if err := SaveCSVColumns(filename, data, mapping, true, true); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"Server", "Port"}, {"web", "443/tcp"}, {"mail;smtp", "465/tcp"}}
+	if !slices.EqualFunc(records, expected, slices.Equal) {
+		t.Errorf("expected %v, got %v", expected, records)
+	}
+	if err := SaveCSVColumns(filepath.Join(t.TempDir(), "missing", "report.csv"), data, nil, false, false); err == nil {
+		t.Error("expected error for missing folder")
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	columns := []Column{{Field: "CertName"}}
+	for _, format := range []string{FormatJSON, FormatNDJSON, FormatTemplate} {
+		options := OutputOptions{Filename: filepath.Join(t.TempDir(), "report"), Format: format, Template: "report.tmpl", Columns: columns}
+		if err := save(options, nil); !errors.Is(err, ErrColumnsNotSupported) {
+			t.Errorf("%s: expected %v, got %v", format, ErrColumnsNotSupported, err)
+		}
+	}
+	for _, format := range []string{FormatCSV, FormatHTML, FormatXLSX} {
+		if err := checkColumns(format, OutputOptions{Columns: columns}); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package certlist
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/backend"
+	"github.com/mpkondrashin/certlist/pkg/dumpreader"
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+// load loads dump from the backup into the database backend. Returned
+// function closes connection and stops the backend.
+func load(ctx context.Context, kind string, options backend.Options, backupPath, passphrase string) (*sql.DB, func(), error) {
+	log.Print("Open dump")
+	dump, err := smsbackup.OpenDump(backupPath, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("OpenDump: %w", err)
+	}
+	defer dump.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go LogProgress(dump, done)
+	filtered := dumpreader.NewFilterReader(dump, smsbackup.ReportTables)
+	defer filtered.Close()
+	return LoadDatabase(ctx, kind, options, filtered)
+}
+
+// LoadDatabase loads dump into database backend of given kind. Returned
+// function closes connection and stops the backend.
+func LoadDatabase(ctx context.Context, kind string, options backend.Options, dump io.Reader) (*sql.DB, func(), error) {
+	db, err := backend.New(kind, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := func() {
+		log.Print("Stop database")
+		if err := db.Stop(); err != nil {
+			log.Print(err)
+		}
+	}
+	log.Printf("Prepare %s database", kind)
+	if err := db.Prepare(ctx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("prepare: %w", err)
+	}
+	log.Print("Start database")
+	if err := db.Start(ctx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("start: %w", err)
+	}
+	log.Print("Load dump")
+	if err := db.Load(ctx, dump); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("load: %w", err)
+	}
+	log.Print("Connect to database")
+	conn, err := db.Open()
+	if err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("connect: %w", err)
+	}
+	return conn, func() {
+		if err := conn.Close(); err != nil {
+			log.Print(err)
+		}
+		stop()
+	}, nil
+}
+
+// LogProgress periodically logs amount of loaded dump data until done is closed.
+func LogProgress(dump *smsbackup.DumpReader, done <-chan struct{}) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			log.Printf("Loaded %s of %s", formatFileSize(dump.Count()), formatFileSize(dump.Size()))
+		}
+	}
+}
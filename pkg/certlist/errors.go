@@ -0,0 +1,34 @@
+package certlist
+
+import "errors"
+
+// Stages of the pipeline. Errors returned by Run match one of them
+// with errors.Is.
+var (
+	ErrBackup   = errors.New("backup")
+	ErrTransfer = errors.New("transfer")
+	ErrLoad     = errors.New("load")
+	ErrQuery    = errors.New("query")
+	ErrRender   = errors.New("render")
+)
+
+// StageError is an error occurred on particular stage of the pipeline.
+type StageError struct {
+	Stage error
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Stage.Error() + ": " + e.Err.Error()
+}
+
+func (e *StageError) Unwrap() []error {
+	return []error{e.Stage, e.Err}
+}
+
+func stageError(stage error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &StageError{Stage: stage, Err: err}
+}
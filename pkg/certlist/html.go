@@ -0,0 +1,185 @@
+package certlist
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+//go:embed dashboard.html
+var dashboardTemplate string
+
+var dashboard = template.Must(template.New("dashboard").Parse(dashboardTemplate))
+
+// noneGroup is a name of the group of certificates not deployed to any
+// device or proxy.
+const noneGroup = "(none)"
+
+// htmlCount is a number of certificates having given status.
+type htmlCount struct {
+	Status string
+	Count  int
+	// Width of the bar in percents of the largest one
+	Width int
+}
+
+// htmlGroup is a breakdown of certificates by status.
+type htmlGroup struct {
+	Name   string
+	Total  int
+	Counts []htmlCount
+}
+
+// htmlDashboard is data of the dashboard template.
+type htmlDashboard struct {
+	Generated    string
+	Certificates int
+	Devices      int
+	Findings     int
+	Statuses     []htmlCount
+	Headers      []string
+	Rows         []htmlRow
+	Timeline     []htmlGroup
+	IPS          []htmlGroup
+}
+
+// htmlRow is a report line. Cells are the same as in CSV report.
+type htmlRow struct {
+	Status string
+	Cells  []string
+}
+
+// SaveHTML writes report as single file HTML dashboard with summary counts,
+// expiry timeline by month, breakdown by IPS and sortable and filterable
+// table of report lines. Table has the same columns as CSV report (see
+// SaveCSVColumns).
+func SaveHTML(filename string, report []smsbackup.ReportLine, mapping []Column, useTags bool) error {
+	columns, err := outputColumns[smsbackup.ReportLine](mapping, useTags)
+	if err != nil {
+		return err
+	}
+	data := htmlDashboard{
+		Generated: time.Now().Format(time.RFC1123),
+		Headers:   headers(columns),
+	}
+	type key struct {
+		sms, certificate, thumbprint string
+	}
+	type deviceKey struct {
+		key
+		device string
+	}
+	seen := make(map[key]bool)
+	seenDevices := make(map[deviceKey]bool)
+	statuses := make(map[string]int)
+	months := make(map[string]map[string]int)
+	devices := make(map[string]map[string]int)
+	for i := range report {
+		line := &report[i]
+		cells, err := row(*line, columns)
+		if err != nil {
+			return err
+		}
+		data.Rows = append(data.Rows, htmlRow{line.Status, cells})
+		r := line.Record()
+		k := key{line.SMS, line.CertName, line.Thumbprint}
+		for _, name := range deviceNames(line) {
+			if seenDevices[deviceKey{k, name}] {
+				continue
+			}
+			seenDevices[deviceKey{k, name}] = true
+			if devices[name] == nil {
+				devices[name] = make(map[string]int)
+			}
+			devices[name][line.Status]++
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		data.Certificates++
+		data.Findings += len(line.FindingList)
+		statuses[line.Status]++
+		if !r.NotAfter.IsZero() {
+			month := r.NotAfter.UTC().Format("2006-01")
+			if months[month] == nil {
+				months[month] = make(map[string]int)
+			}
+			months[month][line.Status]++
+		}
+	}
+	data.Statuses = statusCounts(statuses)
+	for _, name := range sortedKeys(months) {
+		data.Timeline = append(data.Timeline, htmlGroup{Name: name, Counts: statusCounts(months[name])})
+	}
+	for _, name := range sortedKeys(devices) {
+		if name != noneGroup {
+			data.Devices++
+		}
+		data.IPS = append(data.IPS, htmlGroup{Name: name, Counts: statusCounts(devices[name])})
+	}
+	setWidths(data.Timeline)
+	setWidths(data.IPS)
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := dashboard.Execute(file, data); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// deviceNames returns unique names of the devices of the line or noneGroup
+// if there are none.
+func deviceNames(line *smsbackup.ReportLine) []string {
+	var names []string
+	for _, d := range line.Deployments() {
+		if d.Device != nil && !slices.Contains(names, d.Device.Name) {
+			names = append(names, d.Device.Name)
+		}
+	}
+	if len(names) == 0 {
+		return []string{noneGroup}
+	}
+	return names
+}
+
+// statusCounts returns counts in smsbackup.Statuses order.
+func statusCounts(counts map[string]int) []htmlCount {
+	result := make([]htmlCount, len(smsbackup.Statuses))
+	for i, status := range smsbackup.Statuses {
+		result[i] = htmlCount{Status: status, Count: counts[status]}
+	}
+	return result
+}
+
+// setWidths sets totals and bar widths relative to the largest total.
+func setWidths(groups []htmlGroup) {
+	largest := 0
+	for i := range groups {
+		for _, c := range groups[i].Counts {
+			groups[i].Total += c.Count
+		}
+		largest = max(largest, groups[i].Total)
+	}
+	for i := range groups {
+		for j := range groups[i].Counts {
+			groups[i].Counts[j].Width = 100 * groups[i].Counts[j].Count / largest
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,57 @@
+package certlist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveHTML(t *testing.T) {
+	report := testReport(t)
+	filename := filepath.Join(t.TempDir(), "report.html")
+	if err := SaveHTML(filename, report, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	summary, rest, _ := strings.Cut(html, "<h2>Expiry Timeline</h2>")
+	timeline, rest, _ := strings.Cut(rest, "<h2>IPS Devices</h2>")
+	ips, table, _ := strings.Cut(rest, "<h2>Certificates</h2>")
+	shared := report[0].Record().NotAfter.UTC().Format("2006-01")
+	expired := report[2].Record().NotAfter.UTC().Format("2006-01")
+	testCases := []struct {
+		name    string
+		section string
+		lines   []string
+	}{
+		{"summary", summary, []string{
+			`<div class="count">2</div><div class="label">certificates</div>`,
+			`<div class="count">1</div><div class="label">expired</div>`,
+			`<div class="count">1</div><div class="label">warning</div>`,
+			`<div class="count">2</div><div class="label">IPS devices</div>`,
+		}},
+		{"timeline", timeline, []string{
+			`<tr><td>` + expired + `</td><td><div class="bar"><div class="expired" style="width: 100%" title="expired: 1"></div></div></td><td>1</td></tr>`,
+			`<tr><td>` + shared + `</td><td><div class="bar"><div class="warning" style="width: 100%" title="warning: 1"></div></div></td><td>1</td></tr>`,
+		}},
+		{"IPS", ips, []string{
+			`<tr><td>(none)</td><td><div class="bar"><div class="expired" style="width: 100%" title="expired: 1"></div></div></td><td>1</td></tr>`,
+			`<tr><td>ips1</td><td><div class="bar"><div class="warning" style="width: 100%" title="warning: 1"></div></div></td><td>1</td></tr>`,
+			`<tr><td>ips2</td><td><div class="bar"><div class="warning" style="width: 100%" title="warning: 1"></div></div></td><td>1</td></tr>`,
+		}},
+	}
+	for _, tc := range testCases {
+		for _, line := range tc.lines {
+			if !strings.Contains(tc.section, line) {
+				t.Errorf("%s: %s is missing in\n%s", tc.name, line, tc.section)
+			}
+		}
+	}
+	if rows := strings.Count(table, `<tr class="`); rows != len(report) {
+		t.Errorf("expected %d table rows, got %d", len(report), rows)
+	}
+}
@@ -0,0 +1,118 @@
+package certlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+// Report formats
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+	FormatHTML   = "html"
+	// FormatTemplate renders report using OutputOptions.Template
+	FormatTemplate = "template"
+)
+
+var (
+	ErrUnknownFormat       = errors.New("unknown report format")
+	ErrColumnsNotSupported = errors.New("output columns are not supported by report format")
+)
+
+// formatExtensions maps filename extensions to report formats.
+var formatExtensions = map[string]string{
+	".json":   FormatJSON,
+	".ndjson": FormatNDJSON,
+	".jsonl":  FormatNDJSON,
+	".xlsx":   FormatXLSX,
+	".html":   FormatHTML,
+	".htm":    FormatHTML,
+}
+
+// outputFormat returns report format. If options.Format is empty, it is
+// FormatTemplate if options.Template is set, otherwise it is chosen by
+// filename extension, CSV by default.
+func outputFormat(options OutputOptions) (string, error) {
+	switch options.Format {
+	case FormatCSV, FormatJSON, FormatNDJSON, FormatXLSX, FormatHTML, FormatTemplate:
+		return options.Format, nil
+	case "":
+		if options.Template != "" {
+			return FormatTemplate, nil
+		}
+		if format, ok := formatExtensions[strings.ToLower(filepath.Ext(options.Filename))]; ok {
+			return format, nil
+		}
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, options.Format)
+}
+
+// checkColumns returns ErrColumnsNotSupported if options.Columns are set
+// for format written without columns mapping.
+func checkColumns(format string, options OutputOptions) error {
+	switch format {
+	case FormatJSON, FormatNDJSON, FormatTemplate:
+		if len(options.Columns) > 0 {
+			return fmt.Errorf("%w: %s", ErrColumnsNotSupported, format)
+		}
+	}
+	return nil
+}
+
+// save writes report to options.Filename in options.Format.
+func save(options OutputOptions, report []smsbackup.ReportLine) error {
+	format, err := outputFormat(options)
+	if err != nil {
+		return err
+	}
+	if err := checkColumns(format, options); err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON:
+		return SaveJSON(options.Filename, report, false)
+	case FormatNDJSON:
+		return SaveJSON(options.Filename, report, true)
+	case FormatXLSX:
+		return SaveXLSX(options.Filename, report, options.Columns)
+	case FormatHTML:
+		return SaveHTML(options.Filename, report, options.Columns, options.Strict)
+	case FormatTemplate:
+		return SaveTemplate(options.Filename, options.Template, report)
+	}
+	return SaveCSVColumns(options.Filename, report, options.Columns, options.Strict, options.Semicolon)
+}
+
+// SaveJSON writes report as JSON array of smsbackup.Record or as record per
+// line if ndjson is true.
+func SaveJSON(filename string, report []smsbackup.ReportLine, ndjson bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	records := smsbackup.Records(report)
+	if !ndjson {
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+		return file.Close()
+	}
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
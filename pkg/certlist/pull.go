@@ -0,0 +1,337 @@
+package certlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Transfer modes
+const (
+	// TransferPush - SMS uploads backup to embedded sFTP receiver
+	TransferPush = "push"
+	// TransferPull - SMS stores backup to location (see TransferOptions)
+	// and certlist downloads it
+	TransferPull = "pull"
+)
+
+// Kinds of pull mode locations
+const (
+	// LocationSFTP - sFTP server, host:/folder
+	LocationSFTP = "sftp"
+	// LocationSMB - SMB share, \\host\share\folder or smb://host/share/folder
+	LocationSMB = "smb"
+	// LocationLocal - SMS local store, local:/folder. Backup is downloaded
+	// from SMS itself over sFTP
+	LocationLocal = "local"
+)
+
+var (
+	ErrUnknownTransferMode = errors.New("unknown transfer mode")
+	ErrBadLocation         = errors.New(`location should be in host:/folder, \\host\share\folder or local:/folder form`)
+	ErrBackupNotFound      = errors.New("backup file not found")
+	ErrUnsupportedLocation = errors.New("only sFTP, SMB and SMS local store locations are supported")
+	ErrNoKnownHosts        = errors.New("known_hosts file is not found")
+)
+
+// TransferOptions control how backup gets from SMS to certlist.
+type TransferOptions struct {
+	// Mode is TransferPush (default) or TransferPull
+	Mode string
+	// Location SMS stores backup to in pull mode: host:/folder (sFTP),
+	// \\host\share\folder or smb://host/share/folder (SMB) or local:/folder
+	// (SMS local store)
+	Location string
+	// Username and Password for Location and Address. SMB username can be
+	// in DOMAIN\user form. SMS account with sFTP access is used for local store
+	Username string
+	Password string
+	// Address of server to download backup from (host or host:port).
+	// Host of Location (SMS for local store) is used if empty
+	Address string
+	// Dir on server at Address with backups (path inside share for SMB).
+	// Folder of Location is used if empty
+	Dir string
+	// KnownHosts file to check sFTP server key. ~/.ssh/known_hosts if empty
+	KnownHosts string
+}
+
+// location is a parsed pull mode location.
+type location struct {
+	kind string
+	// host is empty for local store
+	host string
+	// share is SMB share name
+	share string
+	// dir is a folder on server (inside share for SMB) with leading slash
+	dir string
+	// windows is true for \\host\share form of SMB location
+	windows bool
+}
+
+// smbPrefixes are prefixes of SMB locations.
+var smbPrefixes = []string{`\\`, "//", "smb://", "cifs://"}
+
+// unsupportedLocations are prefixes of locations SMS can back up to, but
+// certlist can not download from.
+var unsupportedLocations = []string{"nfs://", "file://", "http://", "https://"}
+
+// parseLocation parses pull mode location.
+func parseLocation(s string) (location, error) {
+	lower := strings.ToLower(s)
+	for _, prefix := range unsupportedLocations {
+		if strings.HasPrefix(lower, prefix) {
+			return location{}, fmt.Errorf("%w: %s", ErrUnsupportedLocation, s)
+		}
+	}
+	if strings.HasPrefix(lower, LocationLocal+":") {
+		dir := s[len(LocationLocal)+1:]
+		if !strings.HasPrefix(dir, "/") {
+			return location{}, fmt.Errorf("%w: %s", ErrBadLocation, s)
+		}
+		return location{kind: LocationLocal, dir: path.Clean(dir)}, nil
+	}
+	for _, prefix := range smbPrefixes {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		rest := strings.ReplaceAll(s[len(prefix):], `\`, "/")
+		host, rest, _ := strings.Cut(rest, "/")
+		share, dir, _ := strings.Cut(rest, "/")
+		if host == "" || share == "" {
+			return location{}, fmt.Errorf("%w: %s", ErrBadLocation, s)
+		}
+		return location{
+			kind:    LocationSMB,
+			host:    host,
+			share:   share,
+			dir:     path.Clean("/" + dir),
+			windows: prefix == `\\`,
+		}, nil
+	}
+	host, dir, ok := strings.Cut(s, ":/")
+	if !ok || host == "" {
+		return location{}, fmt.Errorf("%w: %s", ErrBadLocation, s)
+	}
+	return location{kind: LocationSFTP, host: host, dir: "/" + dir}, nil
+}
+
+// backupLocation returns location for SMS to store backup name to.
+func (l location) backupLocation(name string) string {
+	switch l.kind {
+	case LocationLocal:
+		return name
+	case LocationSMB:
+		p := "//" + path.Join(l.host, l.share, l.dir, name)
+		if l.windows {
+			return strings.ReplaceAll(p, "/", `\`)
+		}
+		return p
+	default:
+		return l.host + ":" + path.Join(l.dir, name)
+	}
+}
+
+// pullAddress returns host:port and folder to download backups from. Host
+// of local store location is smsAddress.
+func pullAddress(options TransferOptions, l location, smsAddress string) (address, dir string) {
+	address = options.Address
+	if address == "" {
+		address = l.host
+		if l.kind == LocationLocal {
+			address = smsAddress
+		}
+	}
+	port := "22"
+	if l.kind == LocationSMB {
+		port = "445"
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), port)
+	}
+	dir = l.dir
+	if options.Dir != "" {
+		dir = options.Dir
+	}
+	return address, dir
+}
+
+// hostKeyCallback checks sFTP server key against knownHostsPath or
+// ~/.ssh/known_hosts if it is empty. Server key is always checked, as
+// credentials are sent to the server.
+func hostKeyCallback(knownHostsPath string) (ssh.HostKeyCallback, error) {
+	if knownHostsPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNoKnownHosts, err)
+		}
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(knownHostsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoKnownHosts, knownHostsPath)
+	}
+	return callback, err
+}
+
+// Pull downloads backup name stored by SMS at smsAddress to localPath.
+// Downloaded file is removed from the server unless keep is true.
+func Pull(ctx context.Context, options TransferOptions, smsAddress, name, localPath string, keep bool) error {
+	l, err := parseLocation(options.Location)
+	if err != nil {
+		return err
+	}
+	address, dir := pullAddress(options, l, smsAddress)
+	remotePath := path.Join(dir, name)
+	if l.kind == LocationSMB {
+		err = pullSMB(ctx, options, address, l.share, remotePath, localPath, keep)
+	} else {
+		err = pullSFTP(ctx, options, address, remotePath, localPath, keep)
+	}
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// dial connects to address. Connection is closed when ctx is done.
+func dial(ctx context.Context, address string) (net.Conn, func() bool, error) {
+	log.Printf("Connect to %s", address)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	return conn, stop, nil
+}
+
+func pullSFTP(ctx context.Context, options TransferOptions, address, remotePath, localPath string, keep bool) error {
+	callback, err := hostKeyCallback(options.KnownHosts)
+	if err != nil {
+		return err
+	}
+	config := &ssh.ClientConfig{
+		User:            options.Username,
+		Auth:            []ssh.AuthMethod{ssh.Password(options.Password)},
+		HostKeyCallback: callback,
+		Timeout:         30 * time.Second,
+	}
+	conn, stop, err := dial(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer stop()
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		return err
+	}
+	sshClient := ssh.NewClient(sshConn, channels, requests)
+	defer sshClient.Close()
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	log.Printf("Download %s", remotePath)
+	remote, err := client.Open(remotePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrBackupNotFound, remotePath)
+		}
+		return err
+	}
+	err = download(remote, localPath)
+	remote.Close()
+	if err != nil || keep {
+		return err
+	}
+	if err := client.Remove(remotePath); err != nil {
+		log.Printf("Remove %s: %v", remotePath, err)
+	}
+	return nil
+}
+
+// NTSTATUS codes of missing file and folder
+const (
+	statusObjectNameNotFound = 0xC0000034
+	statusObjectPathNotFound = 0xC000003A
+)
+
+func pullSMB(ctx context.Context, options TransferOptions, address, shareName, remotePath, localPath string, keep bool) error {
+	conn, stop, err := dial(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer stop()
+	domain, user, ok := strings.Cut(options.Username, `\`)
+	if !ok {
+		domain, user = "", options.Username
+	}
+	dialer := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     user,
+			Password: options.Password,
+			Domain:   domain,
+		},
+	}
+	session, err := dialer.DialContext(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer session.Logoff()
+	share, err := session.WithContext(ctx).Mount(shareName)
+	if err != nil {
+		return err
+	}
+	defer share.Umount()
+	sharePath := strings.TrimPrefix(remotePath, "/")
+	log.Printf("Download %s", remotePath)
+	remote, err := share.Open(sharePath)
+	if err != nil {
+		var respErr *smb2.ResponseError
+		if errors.As(err, &respErr) && (respErr.Code == statusObjectNameNotFound || respErr.Code == statusObjectPathNotFound) {
+			return fmt.Errorf("%w: %s", ErrBackupNotFound, remotePath)
+		}
+		return err
+	}
+	err = download(remote, localPath)
+	remote.Close()
+	if err != nil || keep {
+		return err
+	}
+	if err := share.Remove(sharePath); err != nil {
+		log.Printf("Remove %s: %v", remotePath, err)
+	}
+	return nil
+}
+
+// download copies remote file to new localPath.
+func download(remote io.Reader, localPath string) error {
+	local, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(local, remote); err != nil {
+		local.Close()
+		return err
+	}
+	return local.Close()
+}
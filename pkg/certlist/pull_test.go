@@ -0,0 +1,243 @@
+package certlist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpServer serves in-memory sFTP on loopback and returns its address and
+// host key.
+func sftpServer(t *testing.T, username, password string) (string, ssh.PublicKey) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if conn.User() != username || string(p) != password {
+				return nil, errors.New("wrong credentials")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config, handlers)
+		}
+	}()
+	return listener.Addr().String(), signer.PublicKey()
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				req.Reply(req.Type == "subsystem", nil)
+			}
+		}()
+		server := sftp.NewRequestServer(channel, handlers)
+		server.Serve()
+		server.Close()
+	}
+}
+
+// putFile uploads file to the server.
+func putFile(t *testing.T, address, name, content string) {
+	conn, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            "user",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.MkdirAll(path.Dir(name)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := client.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPull(t *testing.T) {
+	address, hostKey := sftpServer(t, "user", "secret")
+	dir := t.TempDir()
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{address}, hostKey)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	options := TransferOptions{
+		Mode:       TransferPull,
+		Location:   "sms-share:/backups",
+		Username:   "user",
+		Password:   "secret",
+		Address:    address,
+		KnownHosts: knownHosts,
+	}
+	putFile(t, address, "/backups/backup.gz", "backup")
+	localPath := filepath.Join(dir, "backup.gz")
+	if err := Pull(context.Background(), options, "sms", "backup.gz", localPath, false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "backup" {
+		t.Errorf("expected %q, got %q", "backup", data)
+	}
+	err = Pull(context.Background(), options, "sms", "backup.gz", filepath.Join(dir, "again.gz"), false)
+	if !errors.Is(err, ErrBackupNotFound) {
+		t.Errorf("downloaded backup is not removed: %v", err)
+	}
+
+	otherKeys := filepath.Join(dir, "other_hosts")
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherKey)
+	if err := os.WriteFile(otherKeys, []byte(knownhosts.Line([]string{address}, otherSigner.PublicKey())+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	options.KnownHosts = otherKeys
+	putFile(t, address, "/backups/mitm.gz", "backup")
+	if err := Pull(context.Background(), options, "sms", "mitm.gz", filepath.Join(dir, "mitm.gz"), false); err == nil {
+		t.Error("server with unknown key accepted")
+	}
+
+	t.Setenv("HOME", dir)
+	t.Setenv("USERPROFILE", dir)
+	options.KnownHosts = ""
+	if err := Pull(context.Background(), options, "sms", "mitm.gz", filepath.Join(dir, "nokeys.gz"), false); !errors.Is(err, ErrNoKnownHosts) {
+		t.Errorf("expected %v, got %v", ErrNoKnownHosts, err)
+	}
+}
+
+func TestPullLocalStore(t *testing.T) {
+	address, hostKey := sftpServer(t, "user", "secret")
+	dir := t.TempDir()
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{address}, hostKey)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	options := TransferOptions{
+		Mode:       TransferPull,
+		Location:   "local:/backups",
+		Username:   "user",
+		Password:   "secret",
+		KnownHosts: knownHosts,
+	}
+	putFile(t, address, "/backups/backup.gz", "backup")
+	localPath := filepath.Join(dir, "backup.gz")
+	// SMS address is used to download backup from its local store
+	if err := Pull(context.Background(), options, address, "backup.gz", localPath, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "backup" {
+		t.Errorf("expected %q, got %q", "backup", data)
+	}
+	if err := Pull(context.Background(), options, address, "backup.gz", filepath.Join(dir, "kept.gz"), false); err != nil {
+		t.Errorf("kept backup: %v", err)
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	testCases := []struct {
+		location string
+		expected location
+		backup   string
+		address  string
+	}{
+		{"10.0.0.5:/backups", location{kind: LocationSFTP, host: "10.0.0.5", dir: "/backups"},
+			"10.0.0.5:/backups/b.gz", "10.0.0.5:22"},
+		{"[fd00::5]:/", location{kind: LocationSFTP, host: "[fd00::5]", dir: "/"},
+			"[fd00::5]:/b.gz", "[fd00::5]:22"},
+		{`\\fileserver\backups\sms`, location{kind: LocationSMB, host: "fileserver", share: "backups", dir: "/sms", windows: true},
+			`\\fileserver\backups\sms\b.gz`, "fileserver:445"},
+		{"smb://fileserver/backups", location{kind: LocationSMB, host: "fileserver", share: "backups", dir: "/"},
+			"//fileserver/backups/b.gz", "fileserver:445"},
+		{"//fileserver/backups/a/b/", location{kind: LocationSMB, host: "fileserver", share: "backups", dir: "/a/b"},
+			"//fileserver/backups/a/b/b.gz", "fileserver:445"},
+		{"local:/var/backups", location{kind: LocationLocal, dir: "/var/backups"},
+			"b.gz", "sms:22"},
+	}
+	for _, tc := range testCases {
+		l, err := parseLocation(tc.location)
+		if err != nil {
+			t.Errorf("%s: %v", tc.location, err)
+			continue
+		}
+		if l != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.location, tc.expected, l)
+		}
+		if backup := l.backupLocation("b.gz"); backup != tc.backup {
+			t.Errorf("%s: expected backup location %s, got %s", tc.location, tc.backup, backup)
+		}
+		if address, _ := pullAddress(TransferOptions{Location: tc.location}, l, "sms"); address != tc.address {
+			t.Errorf("%s: expected address %s, got %s", tc.location, tc.address, address)
+		}
+	}
+	for _, location := range []string{"backups", ":/backups", `\\fileserver`, "smb://fileserver/", "local:backups"} {
+		if _, err := parseLocation(location); !errors.Is(err, ErrBadLocation) {
+			t.Errorf("%s: expected %v, got %v", location, ErrBadLocation, err)
+		}
+	}
+	if _, err := parseLocation("nfs://fileserver/backups"); !errors.Is(err, ErrUnsupportedLocation) {
+		t.Errorf("expected %v, got %v", ErrUnsupportedLocation, err)
+	}
+	address, dir := pullAddress(TransferOptions{Address: "10.0.0.6:2222", Dir: "/in"}, location{kind: LocationLocal, dir: "/out"}, "sms")
+	if address != "10.0.0.6:2222" || dir != "/in" {
+		t.Errorf("unexpected %s %s", address, dir)
+	}
+}
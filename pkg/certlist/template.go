@@ -0,0 +1,171 @@
+package certlist
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+var ErrNoTemplate = errors.New("template filename is not set")
+
+// TemplateData is data the report template is executed with.
+type TemplateData struct {
+	// Generated is time of the report generation
+	Generated time.Time
+	// Records are typed report lines (see smsbackup.Record)
+	Records []smsbackup.Record
+	// Lines are report lines with the same values as in CSV report
+	Lines []smsbackup.ReportLine
+}
+
+// TemplateGroup is a group of records returned by byDevice and byProxy
+// template functions.
+type TemplateGroup struct {
+	Name    string
+	Records []smsbackup.Record
+}
+
+// templateFuncs are helper functions available to report templates:
+//
+//	date layout t         formats time using Go layout, e.g. "2006-01-02"
+//	daysToExpiry t        full days left till t, negative if it has passed
+//	join sep list         joins list using sep separator
+//	byDevice records      groups records by IPS device name
+//	byProxy records       groups records by SSL server proxy name
+//
+// Records without devices or proxies are in the group named "(none)".
+var templateFuncs = template.FuncMap{
+	"date":         templateDate,
+	"daysToExpiry": daysToExpiry,
+	"join":         join,
+	"byDevice": func(records []smsbackup.Record) []TemplateGroup {
+		return groupRecords(records, func(r smsbackup.Record) (names []string) {
+			for _, d := range r.Devices {
+				names = append(names, d.Name)
+			}
+			return
+		})
+	},
+	"byProxy": func(records []smsbackup.Record) []TemplateGroup {
+		return groupRecords(records, func(r smsbackup.Record) []string {
+			return r.SSLServerProxies
+		})
+	},
+}
+
+// loadTemplate parses report template file.
+func loadTemplate(filename string) (*template.Template, error) {
+	if filename == "" {
+		return nil, ErrNoTemplate
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(filename)).Funcs(templateFuncs).Parse(string(data))
+}
+
+// SaveTemplate writes report rendered with text/template from templateFilename.
+func SaveTemplate(filename, templateFilename string, report []smsbackup.ReportLine) error {
+	tmpl, err := loadTemplate(templateFilename)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data := TemplateData{
+		Generated: time.Now(),
+		Records:   smsbackup.Records(report),
+		Lines:     report,
+	}
+	if err := tmpl.Execute(file, data); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// templateDate formats t using layout. Empty string is returned for zero time.
+func templateDate(layout string, t any) (string, error) {
+	switch v := t.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	}
+	return "", fmt.Errorf("date: unsupported type %T", t)
+}
+
+// daysToExpiry returns full days left till t.
+func daysToExpiry(t time.Time) int {
+	return int(math.Floor(time.Until(t).Hours() / 24))
+}
+
+// join joins list using sep. Argument order allows pipelines:
+// {{.SSLServerProxies | join ", "}}.
+func join(sep string, list any) (string, error) {
+	switch v := list.(type) {
+	case []string:
+		return strings.Join(v, sep), nil
+	case []smsbackup.RecordDevice:
+		var names []string
+		for _, d := range v {
+			names = append(names, d.Name)
+		}
+		return strings.Join(names, sep), nil
+	case []smsbackup.RecordClientProxy:
+		var names []string
+		for _, p := range v {
+			names = append(names, p.Name)
+		}
+		return strings.Join(names, sep), nil
+	case []smsbackup.RecordPort:
+		var ports []string
+		for _, p := range v {
+			if p.End != 0 {
+				ports = append(ports, fmt.Sprintf("%d-%d", p.Start, p.End))
+				continue
+			}
+			ports = append(ports, fmt.Sprint(p.Start))
+		}
+		return strings.Join(ports, sep), nil
+	}
+	return "", fmt.Errorf("join: unsupported type %T", list)
+}
+
+// groupRecords groups records by names returned by key preserving order of
+// first appearance. Record having several names is put to each group.
+func groupRecords(records []smsbackup.Record, key func(smsbackup.Record) []string) []TemplateGroup {
+	var groups []TemplateGroup
+	for _, r := range records {
+		names := key(r)
+		if len(names) == 0 {
+			names = []string{noneGroup}
+		}
+		for _, name := range names {
+			i := slices.IndexFunc(groups, func(g TemplateGroup) bool { return g.Name == name })
+			if i == -1 {
+				i = len(groups)
+				groups = append(groups, TemplateGroup{Name: name})
+			}
+			groups[i].Records = append(groups[i].Records, r)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,140 @@
+package certlist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+func TestTemplateDate(t *testing.T) {
+	date := time.Date(2030, 5, 17, 10, 30, 0, 0, time.UTC)
+	var nilTime *time.Time
+	testCases := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"time", date, "2030-05-17 10:30"},
+		{"pointer", &date, "2030-05-17 10:30"},
+		{"zero", time.Time{}, ""},
+		{"zero pointer", &time.Time{}, ""},
+		{"nil", nilTime, ""},
+	}
+	for _, tc := range testCases {
+		actual, err := templateDate("2006-01-02 15:04", tc.value)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, actual)
+		}
+	}
+	if _, err := templateDate("2006-01-02", "2030-05-17"); err == nil {
+		t.Error("expected error for string")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	testCases := []struct {
+		name     string
+		list     any
+		expected string
+	}{
+		{"strings", []string{"web", "mail"}, "web, mail"},
+		{"devices", []smsbackup.RecordDevice{{Name: "ips1", IP: "10.0.0.1"}, {Name: "ips2"}}, "ips1, ips2"},
+		{"client proxies", []smsbackup.RecordClientProxy{{Name: "outbound", BlockExpired: true}}, "outbound"},
+		{"ports", []smsbackup.RecordPort{{Protocol: "HTTP", Start: 443}, {Protocol: "SMTP", Start: 465, End: 470}}, "443, 465-470"},
+		{"empty", []string(nil), ""},
+	}
+	for _, tc := range testCases {
+		actual, err := join(", ", tc.list)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, actual)
+		}
+	}
+	if _, err := join(", ", []int{1, 2}); err == nil {
+		t.Error("expected error for []int")
+	}
+}
+
+func TestGroupRecords(t *testing.T) {
+	records := smsbackup.Records(testReport(t))
+	byDevice := templateFuncs["byDevice"].(func([]smsbackup.Record) []TemplateGroup)
+	byProxy := templateFuncs["byProxy"].(func([]smsbackup.Record) []TemplateGroup)
+	testCases := []struct {
+		name     string
+		groups   []TemplateGroup
+		expected []string
+	}{
+		{"byDevice", byDevice(records), []string{"ips1: shared", "ips2: shared", "(none): expired"}},
+		{"byProxy", byProxy(records), []string{"web: shared,shared", "mail: shared", "(none): expired"}},
+	}
+	for _, tc := range testCases {
+		var actual []string
+		for _, g := range tc.groups {
+			var names []string
+			for _, r := range g.Records {
+				names = append(names, r.Name)
+			}
+			actual = append(actual, g.Name+": "+strings.Join(names, ","))
+		}
+		if strings.Join(actual, "; ") != strings.Join(tc.expected, "; ") {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestExampleTemplates(t *testing.T) {
+	report := testReport(t)
+	shared := report[0].Record()
+	expired := report[2].Record()
+	sharedDate := shared.NotAfter.Format("2006-01-02")
+	expiredDate := expired.NotAfter.Format("2006-01-02")
+	testCases := []struct {
+		template string
+		lines    []string
+	}{
+		{"markdown.tmpl", []string{
+			"# Certificates Report",
+			fmt.Sprintf("| shared | CN=shared | %s | %d | warning | ips1 | web, mail | 443, 465-470 |", sharedDate, shared.DaysToExpiry),
+			fmt.Sprintf("| shared | CN=shared | %s | %d | warning | ips2 | web | 443 |", sharedDate, shared.DaysToExpiry),
+			fmt.Sprintf("| expired | CN=expired | %s | %d | expired |  |  |  |", expiredDate, expired.DaysToExpiry),
+			"### ips2\n\n- shared expires " + sharedDate + " (warning)\n",
+			"### (none)\n\n- expired expires " + expiredDate + " (expired)\n",
+		}},
+		{"confluence.tmpl", []string{
+			"h1. Certificates Report",
+			fmt.Sprintf("|shared|CN=shared|%s|%d|{color:orange}warning{color}|ips1 |web, mail |", sharedDate, shared.DaysToExpiry),
+			fmt.Sprintf("|expired|CN=expired|%s|%d|{color:red}expired{color}| | |", expiredDate, expired.DaysToExpiry),
+			"h3. mail\n\n* shared expires " + sharedDate + " (warning)\n",
+			"h3. (none)\n\n* expired expires " + expiredDate + " (expired)\n",
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "report.txt")
+			if err := SaveTemplate(filename, filepath.Join("..", "..", "cmd", "certlist", "templates", tc.template), report); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range tc.lines {
+				if !strings.Contains(string(data), line) {
+					t.Errorf("%q is missing in\n%s", line, data)
+				}
+			}
+		})
+	}
+}
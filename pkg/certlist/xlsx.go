@@ -0,0 +1,356 @@
+package certlist
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+const (
+	xlsxDateFormat = "yyyy-mm-dd hh:mm"
+	xlsxColWidth   = 20
+)
+
+// xlsxColour is a fill colour of the rows having value in colouring column.
+type xlsxColour struct {
+	value  string
+	colour string
+}
+
+var (
+	statusColours = []xlsxColour{
+		{smsbackup.StatusExpired, "FFC7CE"},
+		{smsbackup.StatusCritical, "FFC7CE"},
+		{smsbackup.StatusWarning, "FFEB9C"},
+	}
+	severityColours = []xlsxColour{
+		{smsbackup.SeverityHigh, "FFC7CE"},
+		{smsbackup.SeverityMedium, "FFEB9C"},
+	}
+)
+
+// xlsxSheet is a table to be written to workbook sheet.
+type xlsxSheet struct {
+	name    string
+	headers []string
+	rows    [][]any
+	// colourBy is a header of the column used to colour rows, none if empty
+	colourBy string
+	colours  []xlsxColour
+}
+
+// SaveXLSX writes report as Excel workbook with Certificates, Devices,
+// SSL Server Proxies and Findings sheets. If mapping is not empty,
+// Certificates sheet has the same columns as CSV report (see SaveCSVColumns).
+func SaveXLSX(filename string, report []smsbackup.ReportLine, mapping []Column) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	certificates := certificatesSheet(report)
+	if len(mapping) > 0 {
+		var err error
+		if certificates, err = mappedSheet(report, mapping); err != nil {
+			return err
+		}
+	}
+	sheets := []xlsxSheet{
+		certificates,
+		devicesSheet(report),
+		proxiesSheet(report),
+		findingsSheet(report),
+	}
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			return err
+		}
+		if err := writeSheet(f, sheet); err != nil {
+			return fmt.Errorf("%s: %w", sheet.name, err)
+		}
+	}
+	return f.SaveAs(filename)
+}
+
+// writeSheet writes table with frozen header, autofilter and conditional
+// colouring of the rows.
+func writeSheet(f *excelize.File, sheet xlsxSheet) error {
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: strPtr(xlsxDateFormat)})
+	if err != nil {
+		return err
+	}
+	for col, header := range sheet.headers {
+		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+		if err := f.SetCellValue(sheet.name, cell, header); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet.name, cell, cell, headerStyle); err != nil {
+			return err
+		}
+	}
+	for row, values := range sheet.rows {
+		for col, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(col+1, row+2)
+			if t, ok := value.(time.Time); ok {
+				if t.IsZero() {
+					continue
+				}
+				if err := f.SetCellValue(sheet.name, cell, t.UTC()); err != nil {
+					return err
+				}
+				if err := f.SetCellStyle(sheet.name, cell, cell, dateStyle); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.SetCellValue(sheet.name, cell, value); err != nil {
+				return err
+			}
+		}
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(sheet.headers))
+	if err := f.SetColWidth(sheet.name, "A", lastCol, xlsxColWidth); err != nil {
+		return err
+	}
+	if err := f.SetPanes(sheet.name, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+	lastRow := len(sheet.rows) + 1
+	if err := f.AutoFilter(sheet.name, fmt.Sprintf("A1:%s%d", lastCol, lastRow), nil); err != nil {
+		return err
+	}
+	if sheet.colourBy == "" || len(sheet.rows) == 0 {
+		return nil
+	}
+	return colourRows(f, sheet, lastCol, lastRow)
+}
+
+// colourRows adds conditional formats colouring rows by value of
+// sheet.colourBy column.
+func colourRows(f *excelize.File, sheet xlsxSheet, lastCol string, lastRow int) error {
+	index := -1
+	for i, header := range sheet.headers {
+		if header == sheet.colourBy {
+			index = i
+		}
+	}
+	if index == -1 {
+		return nil
+	}
+	col, _ := excelize.ColumnNumberToName(index + 1)
+	var formats []excelize.ConditionalFormatOptions
+	for _, c := range sheet.colours {
+		style, err := f.NewConditionalStyle(&excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{c.colour}},
+		})
+		if err != nil {
+			return err
+		}
+		formats = append(formats, excelize.ConditionalFormatOptions{
+			Type:     "formula",
+			Criteria: fmt.Sprintf(`$%s2="%s"`, col, c.value),
+			Format:   &style,
+		})
+	}
+	return f.SetConditionalFormat(sheet.name, fmt.Sprintf("A2:%s%d", lastCol, lastRow), formats)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func certificatesSheet(report []smsbackup.ReportLine) xlsxSheet {
+	sheet := xlsxSheet{
+		name: "Certificates",
+		headers: []string{"Certificate", "SMS", "Subject", "Issuer", "Serial Number",
+			"Effective Date", "Expiration Date", "Days To Expiry", "Status",
+			"Key Algorithm", "Key Size", "Signature Algorithm", "Subject Alt Names", "SHA-256",
+			"Chain Status", "Chain Path", "Root Expiry", "Devices", "SSL Server Proxies",
+			"SSL Client Proxies", "Findings"},
+		colourBy: "Status",
+		colours:  statusColours,
+	}
+	for i := range report {
+		line := &report[i]
+		r := line.Record()
+		sheet.rows = append(sheet.rows, []any{r.Name, r.SMS, r.Subject, r.Issuer, r.SerialNumber,
+			r.NotBefore, r.NotAfter, r.DaysToExpiry, r.Status,
+			r.KeyAlgorithm, r.KeySize, r.SignatureAlgorithm, line.SubjectAltNames, r.SHA256,
+			r.Chain.Status, line.ChainPath, rootExpiry(r), line.IpsName, line.SSLServerProxies,
+			line.SSLClientProxies, line.Findings})
+	}
+	return sheet
+}
+
+// mappedSheet returns Certificates sheet with columns listed in mapping.
+func mappedSheet(report []smsbackup.ReportLine, mapping []Column) (xlsxSheet, error) {
+	columns, err := mapColumns[smsbackup.ReportLine](mapping)
+	if err != nil {
+		return xlsxSheet{}, err
+	}
+	sheet := xlsxSheet{
+		name:    "Certificates",
+		headers: headers(columns),
+		colours: statusColours,
+	}
+	for _, c := range columns {
+		if c.field == "Status" {
+			sheet.colourBy = c.header
+		}
+	}
+	for i := range report {
+		line := &report[i]
+		values, err := row(*line, columns)
+		if err != nil {
+			return xlsxSheet{}, err
+		}
+		r := line.Record()
+		cells := make([]any, len(values))
+		for j, v := range values {
+			cells[j] = v
+			if typed, ok := typedFields[columns[j].field]; ok {
+				cells[j] = typed(r)
+			}
+		}
+		sheet.rows = append(sheet.rows, cells)
+	}
+	return sheet, nil
+}
+
+// typedFields return typed values of the ReportLine fields holding dates
+// and numbers as strings, so mapped columns keep date formatting and sorting.
+var typedFields = map[string]func(r smsbackup.Record) any{
+	"EffectiveDate":  func(r smsbackup.Record) any { return r.NotBefore },
+	"ExpirationDate": func(r smsbackup.Record) any { return r.NotAfter },
+	"RootExpiry":     func(r smsbackup.Record) any { return rootExpiry(r) },
+	"DaysToExpiry":   func(r smsbackup.Record) any { return r.DaysToExpiry },
+}
+
+// rootExpiry returns expiration date of the chain root, zero if root is not found.
+func rootExpiry(r smsbackup.Record) time.Time {
+	if r.Chain.RootExpiry == nil {
+		return time.Time{}
+	}
+	return *r.Chain.RootExpiry
+}
+
+func devicesSheet(report []smsbackup.ReportLine) xlsxSheet {
+	sheet := xlsxSheet{
+		name: "Devices",
+		headers: []string{"Device", "IP", "OS", "Certificate", "Expiration Date",
+			"Days To Expiry", "Status", "SMS"},
+		colourBy: "Status",
+		colours:  statusColours,
+	}
+	type key struct {
+		sms, certificate, thumbprint string
+		device                       uint
+	}
+	seen := make(map[key]bool)
+	for i := range report {
+		line := &report[i]
+		r := line.Record()
+		for _, d := range line.Deployments() {
+			if d.Device == nil {
+				continue
+			}
+			k := key{line.SMS, line.CertName, line.Thumbprint, d.Device.ShortID}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			sheet.rows = append(sheet.rows, []any{d.Device.Name, d.Device.IP, d.Device.TOS, r.Name,
+				r.NotAfter, r.DaysToExpiry, r.Status, r.SMS})
+		}
+	}
+	return sheet
+}
+
+func proxiesSheet(report []smsbackup.ReportLine) xlsxSheet {
+	sheet := xlsxSheet{
+		name: "SSL Server Proxies",
+		headers: []string{"SSL Server Proxy", "Ports", "Certificate", "Expiration Date",
+			"Days To Expiry", "Status", "Devices", "SMS"},
+		colourBy: "Status",
+		colours:  statusColours,
+	}
+	type key struct {
+		sms, certificate, thumbprint, proxy string
+	}
+	index := make(map[key]int)
+	for i := range report {
+		line := &report[i]
+		r := line.Record()
+		for _, d := range line.Deployments() {
+			if d.SSLServer == nil {
+				continue
+			}
+			k := key{line.SMS, line.CertName, line.Thumbprint, d.SSLServer.ID}
+			row, ok := index[k]
+			if !ok {
+				row = len(sheet.rows)
+				index[k] = row
+				sheet.rows = append(sheet.rows, []any{d.SSLServer.Name, formatPorts(d.Ports), r.Name,
+					r.NotAfter, r.DaysToExpiry, r.Status, "", r.SMS})
+			}
+			if d.Device != nil {
+				sheet.rows[row][6] = appendName(sheet.rows[row][6].(string), d.Device.Name)
+			}
+		}
+	}
+	return sheet
+}
+
+func findingsSheet(report []smsbackup.ReportLine) xlsxSheet {
+	sheet := xlsxSheet{
+		name:     "Findings",
+		headers:  []string{"Rule", "Severity", "Message", "Certificate", "Thumbprint", "Devices", "SMS"},
+		colourBy: "Severity",
+		colours:  severityColours,
+	}
+	for _, f := range smsbackup.FindingsReport(report) {
+		sheet.rows = append(sheet.rows, []any{f.Rule, f.Severity, f.Message, f.Certificate,
+			f.Thumbprint, f.Devices, f.SMS})
+	}
+	return sheet
+}
+
+// formatPorts returns comma separated ports and port ranges.
+func formatPorts(ports []smsbackup.Port) string {
+	var result []string
+	for _, p := range ports {
+		if p.End != 0 {
+			result = append(result, fmt.Sprintf("%d-%d", p.Start, p.End))
+			continue
+		}
+		result = append(result, fmt.Sprint(p.Start))
+	}
+	return strings.Join(result, ",")
+}
+
+// appendName appends name to comma separated list if it is not there.
+func appendName(list, name string) string {
+	if list == "" {
+		return name
+	}
+	for _, n := range strings.Split(list, ",") {
+		if n == name {
+			return list
+		}
+	}
+	return list + "," + name
+}
@@ -0,0 +1,199 @@
+package certlist
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
+)
+
+// testCertificate returns PEM of self-signed certificate valid till notAfter.
+func testCertificate(t *testing.T, name string, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// testReport returns report in device layout with "shared" certificate used
+// by "web" SSL server on ips1 and ips2 and by "mail" SSL server on ips1, and
+// "expired" certificate not used anywhere.
+func testReport(t *testing.T) []smsbackup.ReportLine {
+	ips1 := &smsbackup.Device{ShortID: 1, Name: "ips1", IP: "10.0.0.1"}
+	ips2 := &smsbackup.Device{ShortID: 2, Name: "ips2", IP: "10.0.0.2"}
+	web := &smsbackup.SSLServer{ID: "a", Name: "web"}
+	mail := &smsbackup.SSLServer{ID: "b", Name: "mail"}
+	ports := []smsbackup.Port{{Protocol: "HTTP", Start: 443}}
+	notAfter := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	certs := []*smsbackup.Certificate{
+		{Name: "shared", PEM: testCertificate(t, "shared", notAfter), Deployments: []smsbackup.Deployment{
+			{Device: ips1, SSLServer: web, Ports: ports},
+			{Device: ips2, SSLServer: web, Ports: ports},
+			{Device: ips1, SSLServer: mail, Ports: []smsbackup.Port{{Protocol: "SMTP", Start: 465, End: 470}}},
+		}},
+		{Name: "expired", PEM: testCertificate(t, "expired", notAfter.AddDate(0, -2, 0))},
+	}
+	report, err := smsbackup.Render(certs, smsbackup.ReportOptions{CriticalDays: 5, WarningDays: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestSaveXLSX(t *testing.T) {
+	report := testReport(t)
+	filename := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := SaveXLSX(filename, report, nil); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	expectedSheets := []string{"Certificates", "Devices", "SSL Server Proxies", "Findings"}
+	if sheets := f.GetSheetList(); !slices.Equal(sheets, expectedSheets) {
+		t.Fatalf("expected sheets %v, got %v", expectedSheets, sheets)
+	}
+
+	// Expiration Date column of the Certificates sheet
+	for i, line := range report {
+		checkDateCell(t, f, "Certificates", "G"+strconv.Itoa(i+2), line.Record().NotAfter)
+	}
+
+	for _, sheet := range expectedSheets {
+		panes, err := f.GetPanes(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !panes.Freeze || panes.YSplit != 1 || panes.TopLeftCell != "A2" {
+			t.Errorf("%s: header is not frozen: %+v", sheet, panes)
+		}
+	}
+	filters := make(map[string]string)
+	for _, name := range f.GetDefinedName() {
+		if name.Name == "_xlnm._FilterDatabase" {
+			filters[name.Scope] = name.RefersTo
+		}
+	}
+	expectedFilters := map[string]string{
+		"Certificates":       "'Certificates'!$A$1:$U$4",
+		"Devices":            "'Devices'!$A$1:$H$3",
+		"SSL Server Proxies": "'SSL Server Proxies'!$A$1:$H$3",
+		"Findings":           "'Findings'!$A$1:$G$1",
+	}
+	for sheet, expected := range expectedFilters {
+		if filters[sheet] != expected {
+			t.Errorf("%s: expected autofilter %s, got %q", sheet, expected, filters[sheet])
+		}
+	}
+
+	rows, err := f.GetRows("SSL Server Proxies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedProxies := [][]string{
+		{"web", "443", "shared", "ips1,ips2"},
+		{"mail", "465-470", "shared", "ips1"},
+	}
+	if len(rows) != len(expectedProxies)+1 {
+		t.Fatalf("expected %d proxy rows, got %v", len(expectedProxies), rows)
+	}
+	for i, e := range expectedProxies {
+		r := rows[i+1]
+		if r[0] != e[0] || r[1] != e[1] || r[2] != e[2] || r[6] != e[3] {
+			t.Errorf("proxy row %d: expected %v, got %v", i, e, r)
+		}
+	}
+}
+
+func TestSaveXLSXMapping(t *testing.T) {
+	report := testReport(t)
+	filename := filepath.Join(t.TempDir(), "report.xlsx")
+	mapping := []Column{{Field: "CertName", Header: "Certificate"}, {Field: "[ExpirationDate]", Header: "Expires"}, {Field: "DaysToExpiry"}}
+	if err := SaveXLSX(filename, report, mapping); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("Certificates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"Certificate", "Expires", "DaysToExpiry"}; !slices.Equal(rows[0], expected) {
+		t.Errorf("expected headers %v, got %v", expected, rows[0])
+	}
+	for i, line := range report {
+		r := line.Record()
+		checkDateCell(t, f, "Certificates", "B"+strconv.Itoa(i+2), r.NotAfter)
+		cell := "C" + strconv.Itoa(i+2)
+		typ, err := f.GetCellType("Certificates", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := f.GetCellValue("Certificates", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ == excelize.CellTypeSharedString || typ == excelize.CellTypeInlineString || value != strconv.Itoa(r.DaysToExpiry) {
+			t.Errorf("%s: expected number %d, got %q", cell, r.DaysToExpiry, value)
+		}
+	}
+}
+
+// checkDateCell checks that cell holds expected date formatted with xlsxDateFormat.
+func checkDateCell(t *testing.T, f *excelize.File, sheet, cell string, expected time.Time) {
+	t.Helper()
+	value, err := f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("%s: expected date, got %q", cell, value)
+	}
+	date, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := date.Sub(expected.UTC()).Abs(); diff > time.Second {
+		t.Errorf("%s: expected %v, got %v", cell, expected.UTC(), date)
+	}
+	index, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	style, err := f.GetStyle(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.CustomNumFmt == nil || *style.CustomNumFmt != xlsxDateFormat {
+		t.Errorf("%s: expected %q format, got %v", cell, xlsxDateFormat, style.CustomNumFmt)
+	}
+}
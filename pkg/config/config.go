@@ -5,8 +5,12 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/mpkondrashin/certlist/pkg/backend"
+	"github.com/mpkondrashin/certlist/pkg/certlist"
 	"github.com/mpkondrashin/certlist/pkg/maria"
 	"github.com/mpkondrashin/certlist/pkg/prompt"
+	"github.com/mpkondrashin/certlist/pkg/receiver"
+	"github.com/mpkondrashin/certlist/pkg/smsbackup"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -14,6 +18,9 @@ import (
 const (
 	DefaultUsernameLength = 16
 	DefaultPasswordLength = 16
+	DefaultConcurrency    = 2
+	DefaultCriticalDays   = 14
+	DefaultWarningDays    = 60
 )
 
 const (
@@ -32,19 +39,121 @@ const (
 	OutputStrict    = "output.strict"
 	OutputSemicolon = "output.semicolon"
 	OutputNoTZ      = "output.no_tz"
+	OutputLayout    = "output.layout"
+	OutputFormat    = "output.format"
+	OutputTemplate  = "output.template"
+	OutputColumns   = "output.columns"
 
 	SMSAddress         = "sms.address"
 	SMSAPIKey          = "sms.api_key"
 	SMSIgnoreTLSErrors = "sms.ignore_tls_errors"
 
+	SMSServers = "sms_servers"
+
+	AlertCriticalDays = "alert.critical_days"
+	AlertWarningDays  = "alert.warning_days"
+
+	OnlyStatus = "only-status"
+
+	PolicyRules    = "policy.rules"
+	PolicyFindings = "policy.findings"
+
+	BackupConcurrency = "backup.concurrency"
+	BackupMaxAge      = "backup.max_age"
+	BackupCacheDir    = "backup.cache_dir"
+	BackupPassphrase  = "backup.passphrase"
+
 	SFTPUsernameLength = "sftp.username_length"
 	SFTPPasswordLength = "sftp.password_length"
+	SFTPPort           = "sftp.port"
+	SFTPTimeout        = "sftp.timeout"
+
+	TransferMode       = "transfer.mode"
+	TransferLocation   = "transfer.location"
+	TransferUsername   = "transfer.username"
+	TransferPassword   = "transfer.password"
+	TransferAddress    = "transfer.address"
+	TransferDir        = "transfer.dir"
+	TransferKnownHosts = "transfer.known_hosts"
+
+	DatabaseBackend = "database.backend"
+	DatabaseDSN     = "database.dsn"
+	DatabaseName    = "database.name"
+	DatabasePort    = "database.port"
 
 	MariaDB   = "debug.mariadb"
 	Backup    = "debug.backup"
 	NoCleanup = "debug.nocleanup"
 )
 
+// SMSServer is an element of sms_servers list.
+type SMSServer struct {
+	Address         string `mapstructure:"address"`
+	APIKey          string `mapstructure:"api_key"`
+	IgnoreTLSErrors bool   `mapstructure:"ignore_tls_errors"`
+}
+
+// GetSMSServers returns sms_servers list or single server from sms section
+// if list is not provided.
+func GetSMSServers() ([]SMSServer, error) {
+	if !viper.IsSet(SMSServers) {
+		return []SMSServer{{
+			Address:         viper.GetString(SMSAddress),
+			APIKey:          viper.GetString(SMSAPIKey),
+			IgnoreTLSErrors: viper.GetBool(SMSIgnoreTLSErrors),
+		}}, nil
+	}
+	var servers []SMSServer
+	if err := viper.UnmarshalKey(SMSServers, &servers); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// PolicyRule is an element of policy.rules list.
+type PolicyRule struct {
+	Name     string `mapstructure:"name"`
+	Type     string `mapstructure:"type"`
+	Value    string `mapstructure:"value"`
+	Severity string `mapstructure:"severity"`
+}
+
+// GetPolicyRules returns policy.rules list or smsbackup.DefaultRules if list
+// is not provided.
+func GetPolicyRules() ([]smsbackup.Rule, error) {
+	if !viper.IsSet(PolicyRules) {
+		return smsbackup.DefaultRules, nil
+	}
+	var rules []PolicyRule
+	if err := viper.UnmarshalKey(PolicyRules, &rules); err != nil {
+		return nil, err
+	}
+	result := make([]smsbackup.Rule, len(rules))
+	for i, rule := range rules {
+		result[i] = smsbackup.Rule(rule)
+	}
+	return result, nil
+}
+
+// OutputColumn is an element of output.columns list.
+type OutputColumn struct {
+	Field  string `mapstructure:"field"`
+	Header string `mapstructure:"header"`
+}
+
+// GetOutputColumns returns output.columns list.
+func GetOutputColumns() ([]certlist.Column, error) {
+	var columns []OutputColumn
+	if err := viper.UnmarshalKey(OutputColumns, &columns); err != nil {
+		return nil, err
+	}
+	result := make([]certlist.Column, len(columns))
+	for i, column := range columns {
+		result[i] = certlist.Column(column)
+	}
+	return result, nil
+}
+
 func Configure() {
 	fs := pflag.NewFlagSet("", pflag.ExitOnError)
 
@@ -53,13 +162,42 @@ func Configure() {
 	fs.Bool(OutputStrict, false, "Generate strict version of report")
 	fs.Bool(OutputSemicolon, false, "Use semicolon instead of comma as separator")
 	fs.Bool(OutputNoTZ, false, "Do not include timezone in dates")
+	fs.String(OutputLayout, smsbackup.LayoutDevice, "Report layout: device, flat or grouped")
+	fs.String(OutputFormat, "", "Report format: csv, json, ndjson, xlsx, html or template (chosen by filename extension by default)")
+	fs.String(OutputTemplate, "", "Go text/template file to render report with")
 
 	fs.String(SMSAddress, "", "Tipping Point SMS address")
 	fs.String(SMSAPIKey, "", "Tipping Point SMS API Key")
 	fs.Bool(SMSIgnoreTLSErrors, false, "Ignore SMS TLS errors")
 
+	fs.Int(AlertCriticalDays, DefaultCriticalDays, "Certificates expiring in this number of days or less are critical")
+	fs.Int(AlertWarningDays, DefaultWarningDays, "Certificates expiring in this number of days or less are warning")
+	fs.StringSlice(OnlyStatus, nil, "Report only certificates with these statuses: expired, critical, warning, ok, not-yet-valid")
+
+	fs.String(PolicyFindings, "", "Findings report filename")
+
+	fs.Int(BackupConcurrency, DefaultConcurrency, "Number of SMS servers processed in parallel")
+	fs.Duration(BackupMaxAge, 0, "Reuse cached backups not older than this (0 - do not cache)")
+	fs.String(BackupCacheDir, "", "Folder for cached backups")
+	fs.String(BackupPassphrase, "", "Passphrase of encrypted backups")
+
 	fs.Int(SFTPUsernameLength, DefaultUsernameLength, "sFTP username length")
 	fs.Int(SFTPPasswordLength, DefaultPasswordLength, "sFTP password length")
+	fs.Int(SFTPPort, receiver.DefaultPort, "sFTP port to receive backup on")
+	fs.Duration(SFTPTimeout, receiver.DefaultTimeout, "Time to wait for each backup counted from its start")
+
+	fs.String(TransferMode, certlist.TransferPush, "Backup transfer mode: push or pull")
+	fs.String(TransferLocation, "", `Location for SMS to store backup to: host:/folder (sFTP), \\host\share\folder (SMB) or local:/folder (SMS local store) (pull mode)`)
+	fs.String(TransferUsername, "", "sFTP or SMB username, SMS account for local store (pull mode)")
+	fs.String(TransferPassword, "", "Password for transfer username (pull mode)")
+	fs.String(TransferAddress, "", "Server address to download backup from (pull mode)")
+	fs.String(TransferDir, "", "Folder with backups on download server (inside share for SMB) (pull mode)")
+	fs.String(TransferKnownHosts, "", "known_hosts file to check sFTP server key, ~/.ssh/known_hosts by default (pull mode)")
+
+	fs.String(DatabaseBackend, backend.KindMemory, "Database backend: memory, portable, installed or server")
+	fs.String(DatabaseDSN, "", "Existing MySQL/MariaDB server DSN (for server backend)")
+	fs.String(DatabaseName, backend.DefaultServerDatabaseName, "Database name to create on existing server")
+	fs.Int(DatabasePort, 0, "Port for MariaDB to listen on (0 - choose free port)")
 
 	fs.String(MariaDB, maria.MariaDBZip, "MariaDB ZIP file")
 	fs.String(Backup, "", "SMS Backup File")
@@ -93,9 +231,15 @@ func Configure() {
 	mandatory := []string{
 		OutputFilename,
 	}
-	if viper.GetString(Backup) == "" {
+	if viper.GetString(Backup) == "" && !viper.IsSet(SMSServers) {
 		mandatory = append(mandatory, SMSAddress, SMSAPIKey)
 	}
+	if viper.GetString(Backup) == "" && viper.GetString(TransferMode) == certlist.TransferPull {
+		mandatory = append(mandatory, TransferLocation, TransferUsername, TransferPassword)
+	}
+	if viper.GetString(DatabaseBackend) == backend.KindServer {
+		mandatory = append(mandatory, DatabaseDSN)
+	}
 	err = prompt.Mandatory(fs, mandatory...)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,241 @@
+package dumpreader
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DB returns database handle to query the dump. Only queries of the
+// following form are supported:
+//
+//	SELECT col1,col2,... FROM table [WHERE col1=value [AND col2<>value ...]]
+//
+// This is enough for all iterators of pkg/model.
+func (d *Dump) DB() *sql.DB {
+	return sql.OpenDB(&connector{dump: d})
+}
+
+var (
+	ErrNotSupported  = errors.New("not supported by dump reader")
+	ErrUnknownColumn = errors.New("unknown column")
+)
+
+type connector struct {
+	dump *Dump
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{dump: c.dump}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return dumpDriver{}
+}
+
+type dumpDriver struct{}
+
+func (dumpDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("open by name: %w", ErrNotSupported)
+}
+
+type conn struct {
+	dump *Dump
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions: %w", ErrNotSupported)
+}
+
+func (c *conn) Ping(context.Context) error {
+	return nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("query arguments: %w", ErrNotSupported)
+	}
+	return c.dump.query(query)
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return 0
+}
+
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("exec: %w", ErrNotSupported)
+}
+
+func (s *stmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.conn.dump.query(s.query)
+}
+
+var (
+	selectRegexp    = regexp.MustCompile(`(?is)^\s*SELECT\s+(.+?)\s+FROM\s+(\S+)(?:\s+WHERE\s+(.+?))?\s*;?\s*$`)
+	andRegexp       = regexp.MustCompile(`(?i)\s+AND\s+`)
+	conditionRegexp = regexp.MustCompile(`(?is)^(\S+?)\s*(=|!=|<>|\s+IS\s+NOT\s+|\s+IS\s+)\s*(.+)$`)
+)
+
+func (d *Dump) query(query string) (driver.Rows, error) {
+	m := selectRegexp.FindStringSubmatch(query)
+	if m == nil {
+		return nil, fmt.Errorf("%s: %w", query, ErrNotSupported)
+	}
+	t, ok := d.Table(unquote(m[2]))
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", m[2], ErrTableNotFound)
+	}
+	r := &rows{table: t}
+	for _, c := range strings.Split(m[1], ",") {
+		c = unquote(strings.TrimSpace(c))
+		if c == "*" {
+			for i := range t.Columns {
+				r.index = append(r.index, i)
+			}
+			continue
+		}
+		i := t.ColumnIndex(c)
+		if i == -1 {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name, c, ErrUnknownColumn)
+		}
+		r.index = append(r.index, i)
+	}
+	if m[3] != "" {
+		for _, s := range andRegexp.Split(m[3], -1) {
+			cond, err := parseCondition(t, s)
+			if err != nil {
+				return nil, err
+			}
+			r.where = append(r.where, cond)
+		}
+	}
+	return r, nil
+}
+
+// unquote strips backticks and table alias from the name.
+func unquote(name string) string {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	return strings.Trim(name, "`")
+}
+
+type condition struct {
+	column int
+	value  []byte
+	equal  bool
+}
+
+func parseCondition(t *Table, s string) (condition, error) {
+	m := conditionRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return condition{}, fmt.Errorf("%s: %w", s, ErrNotSupported)
+	}
+	cond := condition{column: t.ColumnIndex(unquote(m[1]))}
+	if cond.column == -1 {
+		return condition{}, fmt.Errorf("%s.%s: %w", t.Name, m[1], ErrUnknownColumn)
+	}
+	op := strings.ToUpper(strings.Join(strings.Fields(m[2]), " "))
+	cond.equal = op == "=" || op == "IS"
+	value, err := newLexer(m[3]).value()
+	if err != nil {
+		return condition{}, fmt.Errorf("%s: %w", s, err)
+	}
+	if strings.HasPrefix(op, "IS") != (value == nil) {
+		return condition{}, fmt.Errorf("%s: %w", s, ErrNotSupported)
+	}
+	cond.value = value
+	return cond, nil
+}
+
+func (c condition) match(row [][]byte) bool {
+	v := row[c.column]
+	if c.value == nil {
+		return (v == nil) == c.equal
+	}
+	if v == nil {
+		return false
+	}
+	return equalValues(v, c.value) == c.equal
+}
+
+// equalValues compares values numerically if both are numbers.
+func equalValues(a, b []byte) bool {
+	x, errA := strconv.ParseFloat(string(a), 64)
+	y, errB := strconv.ParseFloat(string(b), 64)
+	if errA == nil && errB == nil {
+		return x == y
+	}
+	return bytes.Equal(a, b)
+}
+
+type rows struct {
+	table *Table
+	index []int
+	where []condition
+	next  int
+}
+
+func (r *rows) Columns() []string {
+	columns := make([]string, len(r.index))
+	for i, n := range r.index {
+		columns[i] = r.table.Columns[n]
+	}
+	return columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	for ; r.next < len(r.table.Rows); r.next++ {
+		row := r.table.Rows[r.next]
+		if !r.match(row) {
+			continue
+		}
+		for i, n := range r.index {
+			if row[n] == nil {
+				dest[i] = nil
+				continue
+			}
+			dest[i] = row[n]
+		}
+		r.next++
+		return nil
+	}
+	return io.EOF
+}
+
+func (r *rows) match(row [][]byte) bool {
+	for _, c := range r.where {
+		if !c.match(row) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+// Package dumpreader reads mysqldump output into memory without database server.
+//
+// Only CREATE TABLE and INSERT statements are interpreted. All other
+// statements are skipped. Loaded tables are available through database/sql
+// interface (see Dump.DB), so pkg/model iterators can be used on them.
+package dumpreader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Table holds columns and rows of a single table. Values are kept in
+// MySQL text protocol form: nil for NULL and bytes for all other values.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][][]byte
+}
+
+// ColumnIndex returns index of the column or -1 if it is not found.
+func (t *Table) ColumnIndex(name string) int {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Dump is a set of tables read from mysqldump file.
+type Dump struct {
+	tables map[string]*Table
+}
+
+// Read parses mysqldump stream.
+func Read(r io.Reader) (*Dump, error) {
+	d := &Dump{
+		tables: make(map[string]*Table),
+	}
+	for stmt, err := range Statements(r) {
+		if err != nil {
+			return nil, err
+		}
+		if err := d.exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Table returns table by name. Name is case insensitive.
+func (d *Dump) Table(name string) (*Table, bool) {
+	t, ok := d.tables[strings.ToUpper(name)]
+	return t, ok
+}
+
+// Tables returns sorted names of all tables.
+func (d *Dump) Tables() (names []string) {
+	for _, t := range d.tables {
+		names = append(names, t.Name)
+	}
+	slices.Sort(names)
+	return
+}
+
+var (
+	ErrSyntax        = errors.New("syntax error")
+	ErrTableNotFound = errors.New("table not found")
+)
+
+func (d *Dump) exec(stmt string) error {
+	l := newLexer(stmt)
+	switch {
+	case l.keywords("CREATE", "TABLE"):
+		return d.createTable(l)
+	case l.keywords("INSERT", "INTO"), l.keywords("INSERT", "IGNORE", "INTO"), l.keywords("REPLACE", "INTO"):
+		return d.insert(l)
+	case l.keywords("DROP", "TABLE"):
+		l.keywords("IF", "EXISTS")
+		name, err := l.identifier()
+		if err != nil {
+			return fmt.Errorf("DROP TABLE: %w", err)
+		}
+		delete(d.tables, strings.ToUpper(name))
+	}
+	return nil
+}
+
+func (d *Dump) createTable(l *lexer) error {
+	l.keywords("IF", "NOT", "EXISTS")
+	name, err := l.identifier()
+	if err != nil {
+		return fmt.Errorf("CREATE TABLE: %w", err)
+	}
+	if !l.symbol('(') {
+		return fmt.Errorf("CREATE TABLE %s: %w: missing column list", name, ErrSyntax)
+	}
+	t := &Table{Name: name}
+	for {
+		if l.isIdentifierStart() && !l.isConstraint() {
+			column, err := l.identifier()
+			if err != nil {
+				return fmt.Errorf("CREATE TABLE %s: %w", name, err)
+			}
+			t.Columns = append(t.Columns, column)
+		}
+		last, err := l.skipDefinition()
+		if err != nil {
+			return fmt.Errorf("CREATE TABLE %s: %w", name, err)
+		}
+		if last == ')' {
+			break
+		}
+	}
+	d.tables[strings.ToUpper(name)] = t
+	return nil
+}
+
+func (d *Dump) insert(l *lexer) error {
+	name, err := l.identifier()
+	if err != nil {
+		return fmt.Errorf("INSERT: %w", err)
+	}
+	t, ok := d.Table(name)
+	if !ok {
+		return fmt.Errorf("INSERT INTO %s: %w", name, ErrTableNotFound)
+	}
+	var index []int
+	if l.symbol('(') {
+		for {
+			column, err := l.identifier()
+			if err != nil {
+				return fmt.Errorf("INSERT INTO %s: %w", name, err)
+			}
+			i := t.ColumnIndex(column)
+			if i == -1 {
+				return fmt.Errorf("INSERT INTO %s: unknown column %s: %w", name, column, ErrSyntax)
+			}
+			index = append(index, i)
+			if l.symbol(')') {
+				break
+			}
+			if !l.symbol(',') {
+				return fmt.Errorf("INSERT INTO %s: %w: bad column list", name, ErrSyntax)
+			}
+		}
+	}
+	if !l.keywords("VALUES") && !l.keywords("VALUE") {
+		return fmt.Errorf("INSERT INTO %s: %w: missing VALUES", name, ErrSyntax)
+	}
+	for {
+		values, err := l.tuple()
+		if err != nil {
+			return fmt.Errorf("INSERT INTO %s: %w", name, err)
+		}
+		row, err := t.row(values, index)
+		if err != nil {
+			return fmt.Errorf("INSERT INTO %s: %w", name, err)
+		}
+		t.Rows = append(t.Rows, row)
+		if !l.symbol(',') {
+			break
+		}
+	}
+	return nil
+}
+
+func (t *Table) row(values [][]byte, index []int) ([][]byte, error) {
+	if index == nil {
+		if len(values) != len(t.Columns) {
+			return nil, fmt.Errorf("%w: got %d values for %d columns", ErrSyntax, len(values), len(t.Columns))
+		}
+		return values, nil
+	}
+	if len(values) != len(index) {
+		return nil, fmt.Errorf("%w: got %d values for %d columns", ErrSyntax, len(values), len(index))
+	}
+	row := make([][]byte, len(t.Columns))
+	for i, v := range values {
+		row[index[i]] = v
+	}
+	return row, nil
+}
@@ -0,0 +1,105 @@
+package dumpreader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mpkondrashin/certlist/pkg/model"
+)
+
+const testDump = `-- MariaDB dump 10.19
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+DROP TABLE IF EXISTS ` + "`SSL_SERVER_PORT`" + `;
+/*!40101 SET character_set_client = utf8 */;
+CREATE TABLE ` + "`SSL_SERVER_PORT`" + ` (
+  ` + "`ID`" + ` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  ` + "`SSL_SERVER_ID`" + ` varchar(255) NOT NULL COMMENT 'id, (server)',
+  ` + "`PROTOCOL_TYPE`" + ` enum('HTTP','SMTP') NOT NULL,
+  ` + "`START_PORT`" + ` int(10) unsigned NOT NULL,
+  ` + "`END_PORT`" + ` int(11) DEFAULT NULL,
+  ` + "`VERSION`" + ` int(10) unsigned NOT NULL,
+  PRIMARY KEY (` + "`ID`" + `),
+  KEY ` + "`FK_SSL_SERVER`" + ` (` + "`SSL_SERVER_ID`" + `)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+LOCK TABLES ` + "`SSL_SERVER_PORT`" + ` WRITE;
+INSERT INTO ` + "`SSL_SERVER_PORT`" + ` VALUES (1,'a;b','HTTP',443,NULL,1),(2,'it''s\'s','SMTP',465,470,2);
+INSERT INTO ` + "`SSL_SERVER_PORT`" + ` (` + "`ID`,`SSL_SERVER_ID`,`PROTOCOL_TYPE`,`START_PORT`,`VERSION`" + `) VALUES (3,'x\ny','HTTP',8443,3);
+UNLOCK TABLES;
+DELIMITER ;;
+/*!50003 CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW BEGIN SET @a = 1; END */;;
+DELIMITER ;
+`
+
+func TestRead(t *testing.T) {
+	dump, err := Read(strings.NewReader(testDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, ok := dump.Table("ssl_server_port")
+	if !ok {
+		t.Fatal("table not found")
+	}
+	expectedColumns := "ID,SSL_SERVER_ID,PROTOCOL_TYPE,START_PORT,END_PORT,VERSION"
+	if actual := strings.Join(table.Columns, ","); actual != expectedColumns {
+		t.Errorf("columns: expected %s, got %s", expectedColumns, actual)
+	}
+	db := dump.DB()
+	defer db.Close()
+	var ports []*model.SslServerPortRow
+	for port, err := range model.RangeSslServerPort(db, "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(ports))
+	}
+	expectedIDs := []string{"a;b", "it's's", "x\ny"}
+	for i, id := range expectedIDs {
+		if ports[i].SslServerID != id {
+			t.Errorf("row %d: expected %q, got %q", i, id, ports[i].SslServerID)
+		}
+	}
+	if ports[0].EndPort.Valid || !ports[1].EndPort.Valid || ports[1].EndPort.Int32 != 470 {
+		t.Errorf("wrong END_PORT values: %v, %v", ports[0].EndPort, ports[1].EndPort)
+	}
+	count := 0
+	for port, err := range model.RangeSslServerPort(db, "START_PORT=8443 AND END_PORT IS NULL") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port.ID != 3 {
+			t.Errorf("expected ID 3, got %d", port.ID)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected 1 row, got %d", count)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var sb strings.Builder
+	if err := Filter(&sb, strings.NewReader(testDump), []string{"ssl_server_port"}); err != nil {
+		t.Fatal(err)
+	}
+	filtered := sb.String()
+	if strings.Count(filtered, "INSERT INTO") != 2 {
+		t.Errorf("INSERT statements are lost:\n%s", filtered)
+	}
+	if strings.Contains(filtered, "TRIGGER") {
+		t.Errorf("trigger is not removed:\n%s", filtered)
+	}
+	sb.Reset()
+	if err := Filter(&sb, strings.NewReader(testDump), []string{"TPT_DEVICE"}); err != nil {
+		t.Fatal(err)
+	}
+	filtered = sb.String()
+	if strings.Contains(filtered, "SSL_SERVER_PORT") {
+		t.Errorf("SSL_SERVER_PORT is not removed:\n%s", filtered)
+	}
+	if !strings.Contains(filtered, "SET @OLD_CHARACTER_SET_CLIENT") || !strings.Contains(filtered, "UNLOCK TABLES") {
+		t.Errorf("statements not related to tables are removed:\n%s", filtered)
+	}
+}
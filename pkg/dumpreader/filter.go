@@ -0,0 +1,92 @@
+package dumpreader
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// conditionalRegexp matches MySQL conditional comments, e.g. /*!40101 SET ... */
+var conditionalRegexp = regexp.MustCompile(`/\*!\d*\s*|\*/`)
+
+// statementTable returns name of the table statement modifies. Second value
+// is false for statements that do not belong to any table (SET, UNLOCK TABLES).
+// Views, triggers and routines are attributed to no table and "" is returned.
+func statementTable(stmt string) (string, bool) {
+	if strings.HasPrefix(stmt, "/*!") {
+		stmt = strings.TrimSpace(conditionalRegexp.ReplaceAllString(stmt, " "))
+	}
+	l := newLexer(stmt)
+	switch {
+	case l.keywords("CREATE", "TABLE"):
+		l.keywords("IF", "NOT", "EXISTS")
+	case l.keywords("CREATE", "DATABASE"):
+		return "", false
+	case l.keywords("CREATE"), l.keywords("DROP", "VIEW"):
+		// Views, triggers, routines and events
+		return "", true
+	case l.keywords("DROP", "TABLE"):
+		l.keywords("IF", "EXISTS")
+	case l.keywords("INSERT", "INTO"),
+		l.keywords("INSERT", "IGNORE", "INTO"),
+		l.keywords("REPLACE", "INTO"),
+		l.keywords("LOCK", "TABLES"),
+		l.keywords("ALTER", "TABLE"):
+	default:
+		return "", false
+	}
+	name, err := l.identifier()
+	if err != nil {
+		return "", true
+	}
+	return name, true
+}
+
+// isSelected checks whether statement should be kept for given tables.
+// Nil tables list selects everything.
+func isSelected(stmt string, tables []string) bool {
+	if tables == nil {
+		return true
+	}
+	name, ok := statementTable(stmt)
+	if !ok {
+		return true
+	}
+	return slices.ContainsFunc(tables, func(t string) bool {
+		return strings.EqualFold(t, name)
+	})
+}
+
+// Filter copies to w only statements related to given tables and
+// statements not related to any table (like SET). Views, triggers and
+// routines are dropped.
+func Filter(w io.Writer, r io.Reader, tables []string) error {
+	bw := bufio.NewWriter(w)
+	for stmt, err := range Statements(r) {
+		if err != nil {
+			return err
+		}
+		if !isSelected(stmt, tables) {
+			continue
+		}
+		if _, err := bw.WriteString(stmt); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(";\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// NewFilterReader returns reader of the dump filtered by Filter function.
+// Reader should be closed to stop filtering goroutine.
+func NewFilterReader(r io.Reader, tables []string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Filter(pw, r, tables))
+	}()
+	return pr
+}
@@ -0,0 +1,322 @@
+package dumpreader
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// lexer splits single SQL statement into tokens.
+type lexer struct {
+	s   string
+	pos int
+}
+
+func newLexer(s string) *lexer {
+	return &lexer{s: s}
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.s) && isSpace(l.s[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *lexer) peek() byte {
+	l.skipSpace()
+	if l.pos == len(l.s) {
+		return 0
+	}
+	return l.s[l.pos]
+}
+
+// keywords consumes given sequence of keywords. If statement does
+// not match, lexer position is not changed.
+func (l *lexer) keywords(words ...string) bool {
+	pos := l.pos
+	for _, w := range words {
+		if !strings.EqualFold(l.word(), w) {
+			l.pos = pos
+			return false
+		}
+	}
+	return true
+}
+
+// word consumes bare word.
+func (l *lexer) word() string {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.s) && isWordChar(l.s[l.pos]) {
+		l.pos++
+	}
+	return l.s[start:l.pos]
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+func (l *lexer) symbol(c byte) bool {
+	if l.peek() != c {
+		return false
+	}
+	l.pos++
+	return true
+}
+
+func (l *lexer) isIdentifierStart() bool {
+	c := l.peek()
+	return c == '`' || isWordChar(c)
+}
+
+var constraints = []string{
+	"PRIMARY", "KEY", "UNIQUE", "INDEX", "CONSTRAINT",
+	"FOREIGN", "FULLTEXT", "SPATIAL", "CHECK", "PERIOD",
+}
+
+// isConstraint checks whether table definition item is not a column.
+func (l *lexer) isConstraint() bool {
+	if l.peek() == '`' {
+		return false
+	}
+	pos := l.pos
+	w := strings.ToUpper(l.word())
+	l.pos = pos
+	return slices.Contains(constraints, w)
+}
+
+// identifier consumes optionally quoted and optionally qualified name
+// and returns its last part.
+func (l *lexer) identifier() (string, error) {
+	for {
+		name, err := l.identifierPart()
+		if err != nil {
+			return "", err
+		}
+		if l.pos < len(l.s) && l.s[l.pos] == '.' {
+			l.pos++
+			continue
+		}
+		return name, nil
+	}
+}
+
+func (l *lexer) identifierPart() (string, error) {
+	if l.peek() != '`' {
+		w := l.word()
+		if w == "" {
+			return "", fmt.Errorf("%w: identifier expected at %d", ErrSyntax, l.pos)
+		}
+		return w, nil
+	}
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		l.pos++
+		if c != '`' {
+			sb.WriteByte(c)
+			continue
+		}
+		if l.pos < len(l.s) && l.s[l.pos] == '`' {
+			sb.WriteByte(c)
+			l.pos++
+			continue
+		}
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("%w: unterminated identifier", ErrSyntax)
+}
+
+// skipDefinition skips the rest of the table definition item and returns
+// the character that terminated it: ',' or ')'.
+func (l *lexer) skipDefinition() (byte, error) {
+	depth := 0
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		switch c {
+		case '\'', '"', '`':
+			if _, err := l.quoted(); err != nil {
+				return 0, err
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				l.pos++
+				return c, nil
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				l.pos++
+				return c, nil
+			}
+		}
+		l.pos++
+	}
+	return 0, fmt.Errorf("%w: unterminated table definition", ErrSyntax)
+}
+
+// tuple consumes list of values in parentheses.
+func (l *lexer) tuple() ([][]byte, error) {
+	if !l.symbol('(') {
+		return nil, fmt.Errorf("%w: '(' expected at %d", ErrSyntax, l.pos)
+	}
+	var values [][]byte
+	for {
+		v, err := l.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if l.symbol(')') {
+			return values, nil
+		}
+		if !l.symbol(',') {
+			return nil, fmt.Errorf("%w: ',' expected at %d", ErrSyntax, l.pos)
+		}
+	}
+}
+
+// value consumes literal value. NULL is returned as nil.
+func (l *lexer) value() ([]byte, error) {
+	c := l.peek()
+	switch {
+	case c == '\'' || c == '"':
+		return l.quoted()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	case c == '_':
+		// Character set introducer, e.g. _binary 'abc'
+		l.word()
+		return l.value()
+	}
+	w := l.word()
+	switch strings.ToUpper(w) {
+	case "NULL":
+		return nil, nil
+	case "TRUE":
+		return []byte("1"), nil
+	case "FALSE":
+		return []byte("0"), nil
+	case "X":
+		s, err := l.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return hexValue(string(s))
+	case "B":
+		s, err := l.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return bitValue(string(s))
+	}
+	return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, w, l.pos)
+}
+
+func (l *lexer) number() ([]byte, error) {
+	start := l.pos
+	if l.s[l.pos] == '-' || l.s[l.pos] == '+' {
+		l.pos++
+	}
+	if strings.HasPrefix(l.s[l.pos:], "0x") || strings.HasPrefix(l.s[l.pos:], "0X") {
+		l.pos += 2
+		hexStart := l.pos
+		for l.pos < len(l.s) && isWordChar(l.s[l.pos]) {
+			l.pos++
+		}
+		return hexValue(l.s[hexStart:l.pos])
+	}
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		isExp := c == 'e' || c == 'E'
+		isExpSign := (c == '-' || c == '+') && (l.s[l.pos-1] == 'e' || l.s[l.pos-1] == 'E')
+		if !(c >= '0' && c <= '9') && c != '.' && !isExp && !isExpSign {
+			break
+		}
+		l.pos++
+	}
+	if l.pos == start {
+		return nil, fmt.Errorf("%w: number expected at %d", ErrSyntax, l.pos)
+	}
+	return []byte(l.s[start:l.pos]), nil
+}
+
+func hexValue(s string) ([]byte, error) {
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	return v, nil
+}
+
+// bitValue converts b'0101' literal to decimal text, so it can be
+// scanned into integer fields.
+func bitValue(s string) ([]byte, error) {
+	var n big.Int
+	if _, ok := n.SetString(s, 2); !ok && s != "" {
+		return nil, fmt.Errorf("%w: bad bit value %q", ErrSyntax, s)
+	}
+	return []byte(n.String()), nil
+}
+
+// quoted consumes quoted string and returns its unescaped value.
+func (l *lexer) quoted() ([]byte, error) {
+	quote := l.s[l.pos]
+	l.pos++
+	v := []byte{}
+	for l.pos < len(l.s) {
+		c := l.s[l.pos]
+		l.pos++
+		if c == '\\' && quote != '`' && l.pos < len(l.s) {
+			v = append(v, unescape(l.s[l.pos])...)
+			l.pos++
+			continue
+		}
+		if c != quote {
+			v = append(v, c)
+			continue
+		}
+		if l.pos < len(l.s) && l.s[l.pos] == quote {
+			v = append(v, c)
+			l.pos++
+			continue
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: unterminated string", ErrSyntax)
+}
+
+// unescape returns character for MySQL escape sequence.
+func unescape(c byte) []byte {
+	switch c {
+	case '0':
+		return []byte{0}
+	case 'b':
+		return []byte{'\b'}
+	case 'n':
+		return []byte{'\n'}
+	case 'r':
+		return []byte{'\r'}
+	case 't':
+		return []byte{'\t'}
+	case 'Z':
+		return []byte{26}
+	case '%', '_':
+		return []byte{'\\', c}
+	default:
+		return []byte{c}
+	}
+}
@@ -0,0 +1,194 @@
+package dumpreader
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+	"strings"
+)
+
+const defaultDelimiter = ";"
+
+// Statements provide iterator over SQL statements of the mysqldump stream.
+// Line comments are dropped, statements are returned without delimiter.
+func Statements(r io.Reader) iter.Seq2[string, error] {
+	return func(yield func(stmt string, err error) bool) {
+		s := &scanner{
+			r:         bufio.NewReaderSize(r, 1024*1024),
+			delimiter: defaultDelimiter,
+		}
+		for {
+			stmt, err := s.next()
+			if errors.Is(err, io.EOF) {
+				if stmt != "" {
+					yield(stmt, nil)
+				}
+				return
+			}
+			if err != nil {
+				yield("", err)
+				return
+			}
+			if stmt == "" {
+				continue
+			}
+			if !yield(stmt, nil) {
+				return
+			}
+		}
+	}
+}
+
+type scanner struct {
+	r         *bufio.Reader
+	delimiter string
+	sb        strings.Builder
+}
+
+// next returns next statement. Empty statement is returned for
+// DELIMITER commands and stray delimiters.
+func (s *scanner) next() (string, error) {
+	s.sb.Reset()
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return strings.TrimSpace(s.sb.String()), err
+		}
+		switch c {
+		case '\'', '"', '`':
+			s.sb.WriteByte(c)
+			if err := s.quoted(c); err != nil {
+				return "", err
+			}
+			continue
+		case '/':
+			if s.peekIs('*') {
+				s.sb.WriteByte(c)
+				if err := s.blockComment(); err != nil {
+					return "", err
+				}
+				continue
+			}
+		case '#':
+			if err := s.skipLine(); err != nil {
+				return "", err
+			}
+			continue
+		case '-':
+			if b, _ := s.r.Peek(2); len(b) == 2 && b[0] == '-' && isSpace(b[1]) {
+				if err := s.skipLine(); err != nil {
+					return "", err
+				}
+				continue
+			}
+		case 'D', 'd':
+			if strings.TrimSpace(s.sb.String()) == "" {
+				isDelimiter, err := s.delimiterCommand()
+				if err != nil {
+					return "", err
+				}
+				if isDelimiter {
+					return "", nil
+				}
+			}
+		}
+		s.sb.WriteByte(c)
+		if c == s.delimiter[len(s.delimiter)-1] && strings.HasSuffix(s.sb.String(), s.delimiter) {
+			stmt := s.sb.String()
+			return strings.TrimSpace(stmt[:len(stmt)-len(s.delimiter)]), nil
+		}
+	}
+}
+
+// delimiterCommand handles DELIMITER command. First letter is already consumed.
+func (s *scanner) delimiterCommand() (bool, error) {
+	b, _ := s.r.Peek(9)
+	if len(b) < 9 || !strings.EqualFold("ELIMITER", string(b[:8])) || !isSpace(b[8]) {
+		return false, nil
+	}
+	line, err := s.r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return true, err
+	}
+	delimiter := strings.TrimSpace(line[8:])
+	if delimiter == "" {
+		return true, ErrBadDelimiter
+	}
+	s.delimiter = delimiter
+	return true, nil
+}
+
+var ErrBadDelimiter = errors.New("empty DELIMITER command")
+
+func (s *scanner) peekIs(c byte) bool {
+	b, _ := s.r.Peek(1)
+	return len(b) == 1 && b[0] == c
+}
+
+func (s *scanner) skipLine() error {
+	_, err := s.r.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// quoted copies quoted string up to (and including) closing quote.
+func (s *scanner) quoted(quote byte) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		s.sb.WriteByte(c)
+		if c == '\\' && quote != '`' {
+			c, err := s.r.ReadByte()
+			if err != nil {
+				return unexpected(err)
+			}
+			s.sb.WriteByte(c)
+			continue
+		}
+		if c == quote {
+			// Doubled quote is an escaped quote
+			if !s.peekIs(quote) {
+				return nil
+			}
+			c, _ := s.r.ReadByte()
+			s.sb.WriteByte(c)
+		}
+	}
+}
+
+// blockComment copies /* ... */ comment. Opening slash is already consumed.
+// Comments are kept as mysqldump puts conditional statements (/*!40101 ... */)
+// into them.
+func (s *scanner) blockComment() error {
+	var prev byte
+	for n := 0; ; n++ {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		s.sb.WriteByte(c)
+		// n > 1 to not treat "/*/" as a complete comment
+		if n > 1 && prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+var ErrUnexpectedEOF = errors.New("unexpected end of dump")
+
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return ErrUnexpectedEOF
+	}
+	return err
+}
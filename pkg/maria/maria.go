@@ -1,33 +1,53 @@
 package maria
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 var (
 	DatabaseName = "sms"
-	MariaDBZip   = "mariadb-latest.zip" //mariadb-11.7.2-winx64.zip"
-	Port         = "33060"
+	MariaDBZip   = defaultDistrib() //mariadb-11.7.2-winx64.zip"
+	ReadyTimeout = 2 * time.Minute
 )
 
+// defaultDistrib returns name of the portable MariaDB for current platform
+// (as it is downloaded by cmd/mdownload).
+func defaultDistrib() string {
+	if runtime.GOOS == "windows" {
+		return "mariadb-latest.zip"
+	}
+	return "mariadb-latest.tar.gz"
+}
+
 type DB struct {
 	distribPath         string
 	tempFolder          string
 	mariadbExe          string
 	mariadbdExe         string
 	mariadbInstallDbExe string
+	baseDir             string
+	port                int
 	cmd                 *exec.Cmd
+	exited              chan struct{}
+	exitErr             error
 }
 
 func NewDB(distribPath, tempFolder string) *DB {
@@ -36,6 +56,28 @@ func NewDB(distribPath, tempFolder string) *DB {
 		tempFolder:  tempFolder,
 	}
 }
+
+// SetPort sets port for MariaDB to listen on. By default free port is chosen on Start.
+func (db *DB) SetPort(port int) *DB {
+	db.port = port
+	return db
+}
+
+// Port returns port MariaDB listens on.
+func (db *DB) Port() int {
+	return db.port
+}
+
+// freePort returns port on loopback interface that is not used at the moment.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
 func (db *DB) DataFolder() string {
 	return filepath.Join(db.tempFolder, "data")
 }
@@ -44,113 +86,256 @@ func (db *DB) MariaFolder() string {
 	return filepath.Join(db.tempFolder, "maria")
 }
 
+// binaries returns names of client, server and install_db executables in distributive.
+func binaries() []string {
+	if runtime.GOOS == "windows" {
+		return []string{
+			"mysql.exe",
+			"mysqld.exe",
+			"mysql_install_db.exe",
+		}
+	}
+	return []string{
+		"mariadb",
+		"mariadbd",
+		"mariadb-install-db",
+	}
+}
+
 func (db *DB) Extract() error {
 	if runtime.GOOS == "darwin" {
-		db.mariadbExe = "/opt/homebrew/bin/mariadb"
-		db.mariadbdExe = "/opt/homebrew/bin/mariadbd"
-		db.mariadbInstallDbExe = "/opt/homebrew/bin/mariadb-install-db"
-		return nil
+		// Homebrew MariaDB is used
+		return db.Locate()
 	}
 
 	if err := os.MkdirAll(db.MariaFolder(), 0755); err != nil {
 		return fmt.Errorf("failed to create folder for maria: %w", err)
 	}
-	paths, err := Unzip(db.distribPath, db.MariaFolder(), []string{
-		"mysql.exe",
-		"mysqld.exe",
-		"mysql_install_db.exe",
-	})
-	/*
-			"mariadb.exe",
-		"mariadbd.exe",
-		"mariadb-install-db.exe",
-
-	*/
+	extract := Unzip
+	if IsTarGz(db.distribPath) {
+		extract = Untar
+	}
+	searchFor := binaries()
+	paths, err := extract(db.distribPath, db.MariaFolder(), searchFor)
 	if err != nil {
-		return fmt.Errorf("failed to unzip MariaDB: %w", err)
+		return fmt.Errorf("failed to extract MariaDB: %w", err)
 	}
-	if paths[0] == "" {
-		return fmt.Errorf("failed to find mariadb.exe in the zip file")
+	for i, path := range paths {
+		if path == "" {
+			return fmt.Errorf("failed to find %s in %s", searchFor[i], db.distribPath)
+		}
 	}
 	db.mariadbExe = paths[0]
-	if paths[1] == "" {
-		return fmt.Errorf("failed to find mariadb.exe in the zip file")
-	}
 	db.mariadbdExe = paths[1]
-	if paths[2] == "" {
-		return fmt.Errorf("failed to find mariadb-install-db.exe in the zip file")
-	}
 	db.mariadbInstallDbExe = paths[2]
+	// Linux distributive has bin/mariadbd and share/ folders in its root
+	db.baseDir = filepath.Dir(filepath.Dir(db.mariadbdExe))
 	return nil
 }
 
-func (db *DB) Init() error {
-	c := exec.Command(db.mariadbInstallDbExe, "--datadir="+db.DataFolder())
+// Locate finds MariaDB binaries installed in the system (in PATH).
+func (db *DB) Locate() (err error) {
+	db.mariadbExe, err = lookPath("mariadb", "mysql")
+	if err != nil {
+		return err
+	}
+	db.mariadbdExe, err = lookPath("mariadbd", "mysqld")
+	if err != nil {
+		return err
+	}
+	db.mariadbInstallDbExe, err = lookPath("mariadb-install-db", "mysql_install_db")
+	return err
+}
+
+// lookPath returns path to the first found executable.
+func lookPath(names ...string) (string, error) {
+	for _, name := range names {
+		path, err := exec.LookPath(name)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", names[0], exec.ErrNotFound)
+}
+
+func (db *DB) Init(ctx context.Context) error {
+	options := []string{
+		"--datadir=" + db.DataFolder(),
+	}
+	if runtime.GOOS != "windows" {
+		options = append(options, "--auth-root-authentication-method=normal")
+		if db.baseDir != "" {
+			options = append(options, "--basedir="+db.baseDir)
+		}
+		options = append(options, rootUserOptions()...)
+	}
+	c := exec.CommandContext(ctx, db.mariadbInstallDbExe, options...)
 	var sb strings.Builder
 	c.Stdout = &sb
 	c.Stderr = &sb
 	err := c.Run()
 	if err != nil {
-		return fmt.Errorf("failed to init MariaDB: %w\n%s %s\n%s", err, db.mariadbInstallDbExe, "--datadir="+db.DataFolder(), sb.String())
+		return fmt.Errorf("failed to init MariaDB: %w\n%s %s\n%s", err, db.mariadbInstallDbExe, strings.Join(options, " "), sb.String())
 	}
 	return nil
 }
 
-func (db *DB) Start() error {
+// Start runs mariadbd. It is killed when ctx is done.
+func (db *DB) Start(ctx context.Context) error {
+	if db.port == 0 {
+		port, err := freePort()
+		if err != nil {
+			return fmt.Errorf("failed to find free port: %w", err)
+		}
+		db.port = port
+	}
 	options := []string{
 		"--datadir=" + db.DataFolder(),
 		"--bind-address=127.0.0.1",
-		"--port=" + Port,
-		"--log-error=" + filepath.Join(db.tempFolder, "mariadb_error.log"),
+		"--port=" + strconv.Itoa(db.port),
+		"--log-error=" + db.ErrorLog(),
 		"--pid-file=" + filepath.Join(db.tempFolder, "mariadb.pid"),
 		"--skip-grant-tables",
-		"--console",
 		"--silent-startup",
 	}
+	if runtime.GOOS == "windows" {
+		options = append(options, "--console")
+	} else {
+		options = append(options, "--socket="+filepath.Join(db.tempFolder, "mariadb.sock"))
+		if db.baseDir != "" {
+			options = append(options, "--basedir="+db.baseDir)
+		}
+		options = append(options, rootUserOptions()...)
+	}
 	//log.Println(db.mariadbdExe, strings.Join(options, " "))
-	db.cmd = exec.Command(db.mariadbdExe, options...)
+	cmd := exec.CommandContext(ctx, db.mariadbdExe, options...)
 	var sb strings.Builder
-	db.cmd.Stdout = &sb
-	db.cmd.Stderr = &sb
-	err := db.cmd.Start()
+	cmd.Stdout = &sb
+	cmd.Stderr = &sb
+	err := cmd.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start MariaDB: %w\n%s %s\n%s", err, db.mariadbdExe, strings.Join(options, " "), sb.String())
 	}
+	// cmd and exited are set only for started process, so Stop is no-op
+	// if Start failed
+	db.cmd = cmd
+	db.exited = make(chan struct{})
+	go func() {
+		db.exitErr = cmd.Wait()
+		close(db.exited)
+	}()
 	return nil
 }
 
+func (db *DB) ErrorLog() string {
+	return filepath.Join(db.tempFolder, "mariadb_error.log")
+}
+
+var (
+	ErrNotReady = errors.New("MariaDB is not ready")
+	ErrExited   = errors.New("MariaDB exited")
+)
+
+// WaitReady waits until started MariaDB accepts connections. It returns error
+// if mariadbd exits or does not become ready within timeout. Error includes
+// the tail of MariaDB error log.
+func (db *DB) WaitReady(ctx context.Context, timeout time.Duration) error {
+	if db.cmd == nil {
+		return fmt.Errorf("%w: not started", ErrNotReady)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		select {
+		case <-db.exited:
+			return fmt.Errorf("%w: %v\n%s", ErrExited, db.exitErr, db.logTail())
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v (%v)\n%s", ErrNotReady, ctx.Err(), lastErr, db.logTail())
+		case <-ticker.C:
+		}
+		lastErr = db.ping(ctx)
+		if lastErr == nil {
+			return nil
+		}
+	}
+}
+
+func (db *DB) ping(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(db.port)))
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	sqlDB, err := db.Open("")
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+	return sqlDB.PingContext(ctx)
+}
+
+const logTailSize = 2048
+
+// logTail returns the end of MariaDB error log.
+func (db *DB) logTail() string {
+	data, err := os.ReadFile(db.ErrorLog())
+	if err != nil {
+		return err.Error()
+	}
+	if len(data) > logTailSize {
+		data = data[len(data)-logTailSize:]
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			data = data[i+1:]
+		}
+	}
+	return string(data)
+}
+
+// rootUserOptions allow to run MariaDB as root, which it refuses to
+// do unless explicitly asked.
+func rootUserOptions() []string {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return []string{"--user=root"}
+}
+
 func (db *DB) Stop() error {
+	if db.cmd == nil {
+		return nil
+	}
 	err := db.cmd.Process.Kill()
-	if err != nil {
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
 		return err
 	}
-	return db.cmd.Wait()
+	<-db.exited
+	return nil
 }
 
 func (db *DB) Open(databasename string) (*sql.DB, error) {
-	dsn := fmt.Sprintf("root:@tcp(127.0.0.1:%s)/%s", Port, databasename)
+	dsn := fmt.Sprintf("root:@tcp(127.0.0.1:%d)/%s", db.port, databasename)
 	return sql.Open("mysql", dsn)
 }
 
 var ErrPopulate = errors.New("failed to populate MariaDB")
 
-func (db *DB) Populate(dumpFile string, databaseName string) error {
+// Populate feeds dump to mariadb client.
+func (db *DB) Populate(ctx context.Context, dump io.Reader, databaseName string) error {
 	command := []string{
 		"-u=root",
 		"--skip-password",
 		"--host=127.0.0.1",
-		"--port=" + Port,
+		"--port=" + strconv.Itoa(db.port),
 		databaseName,
 	}
 	//log.Println(db.mariadbExe, strings.Join(command, " "))
-	c := exec.Command(db.mariadbExe, command...)
-	in, err := os.Open(dumpFile)
-	if err != nil {
-		return fmt.Errorf("failed to open dump file: %w", err)
-	}
-	defer in.Close()
+	c := exec.CommandContext(ctx, db.mariadbExe, command...)
 	var errOutput strings.Builder
-	c.Stdin = in
+	c.Stdin = dump
 	c.Stdout = os.Stdout
 	c.Stderr = &errOutput //os.Stderr
 	if err := c.Run(); err != nil {
@@ -209,6 +394,75 @@ func Unzip(src, dest string, searchFor []string) (paths []string, err error) {
 	return
 }
 
+// IsTarGz checks whether distributive is tar.gz archive.
+func IsTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+func Untar(src, dest string, searchFor []string) (paths []string, err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gzReader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gzReader.Close()
+	tarReader := tar.NewReader(gzReader)
+	paths = make([]string, len(searchFor))
+	destPrefix := filepath.Clean(dest) + string(os.PathSeparator)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return paths, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fpath := filepath.Join(dest, header.Name)
+		for i, each := range searchFor {
+			if filepath.Base(header.Name) == each && header.Typeflag == tar.TypeReg {
+				paths[i] = fpath
+			}
+		}
+		if !filepath.HasPrefix(fpath, destPrefix) {
+			return nil, fmt.Errorf("invalid file path: %s", fpath)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fpath, os.ModePerm); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+				return nil, err
+			}
+			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode())
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(outFile, tarReader)
+			outFile.Close()
+			if err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			target := filepath.Join(filepath.Dir(fpath), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || !filepath.HasPrefix(target, destPrefix) {
+				return nil, fmt.Errorf("invalid link target: %s -> %s", fpath, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+				return nil, err
+			}
+			if err := os.Symlink(header.Linkname, fpath); err != nil && !os.IsExist(err) {
+				return nil, err
+			}
+		}
+	}
+}
+
 func CreateDatabase(db *sql.DB) error {
 	query := fmt.Sprintf("CREATE DATABASE %s", DatabaseName)
 	_, err := db.Exec(query)
@@ -1,11 +1,11 @@
 package maria
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
-	"time"
 
 	"github.com/mpkondrashin/certlist/pkg/model"
 )
@@ -23,18 +23,18 @@ func TestMaria(t *testing.T) {
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		t.Fatal(err)
 	}
-	mariaDistib := "../../cmd/mdownload/mariadb-latest.zip"
+	mariaDistib := filepath.Join("../../cmd/mdownload", MariaDBZip)
 	mariaDB := NewDB(mariaDistib, tempDir)
 	t.Log("Extract database")
 	if err := mariaDB.Extract(); err != nil {
 		t.Logf("Extract: %v", err)
 	}
 	t.Log("Initialize database")
-	if err := mariaDB.Init(); err != nil {
+	if err := mariaDB.Init(context.Background()); err != nil {
 		t.Fatalf("Initialize: %v", err)
 	}
 	t.Log("Start database")
-	if err := mariaDB.Start(); err != nil {
+	if err := mariaDB.Start(context.Background()); err != nil {
 		t.Fatalf("Start: %v", err)
 	}
 	defer func() {
@@ -43,7 +43,9 @@ func TestMaria(t *testing.T) {
 			t.Fatal(err)
 		}
 	}()
-	time.Sleep(2 * time.Second)
+	if err := mariaDB.WaitReady(context.Background(), ReadyTimeout); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
 	t.Logf("Connect to the database")
 	db, err := mariaDB.Open("")
 	if err != nil {
@@ -62,8 +64,12 @@ func TestMaria(t *testing.T) {
 		t.Fatalf("Connect: %v", err)
 	}
 	t.Logf("Populate database")
-	dumpFile := "noalerts.mysqldump"
-	if err := mariaDB.Populate(dumpFile, DatabaseName); err != nil {
+	dumpFile, err := os.Open("noalerts.mysqldump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dumpFile.Close()
+	if err := mariaDB.Populate(context.Background(), dumpFile, DatabaseName); err != nil {
 		t.Fatalf("Populate database: %v", err)
 	}
 	for tpt, err := range model.RangeTptDevice(db, "") {
@@ -73,3 +79,14 @@ func TestMaria(t *testing.T) {
 		t.Logf("Tpt: %s", tpt.DisplayName.String)
 	}
 }
+
+func TestStopAfterFailedStart(t *testing.T) {
+	mariaDB := NewDB("", t.TempDir())
+	mariaDB.mariadbdExe = filepath.Join(t.TempDir(), "missing-mariadbd")
+	if err := mariaDB.Start(context.Background()); err == nil {
+		t.Fatal("Start: expected error")
+	}
+	if err := mariaDB.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
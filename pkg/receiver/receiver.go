@@ -0,0 +1,507 @@
+// Package receiver provides single purpose sFTP server to receive SMS backups.
+//
+// Receiver accepts connections only from expected clients, lets each of them
+// upload only one file with expected name and shuts down when all uploads are
+// finished or failed. Upload fails if it is not finished within timeout after
+// it is armed.
+package receiver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultPort    = 22
+	DefaultTimeout = time.Hour
+)
+
+var (
+	ErrTimeout    = errors.New("upload timeout")
+	ErrIncomplete = errors.New("upload is not finished")
+	ErrStarted    = errors.New("receiver is already started")
+	ErrResolve    = errors.New("client address is not resolved")
+)
+
+// Options of the Receiver.
+type Options struct {
+	// Address to listen on
+	Address string
+	// Port to listen on
+	Port int
+	// Timeout of each upload counted from the moment it is armed (see Receiver.Arm)
+	Timeout time.Duration
+	// UsernameLength and PasswordLength of generated credentials
+	UsernameLength int
+	PasswordLength int
+}
+
+// Upload is a file Receiver expects to get.
+type Upload struct {
+	name      string
+	localPath string
+	clients   []net.IP
+	started   bool
+	finished  bool
+	timer     *time.Timer
+	done      chan struct{}
+	err       error
+}
+
+// Done is closed when upload is finished or failed.
+func (u *Upload) Done() <-chan struct{} {
+	return u.done
+}
+
+// Err returns result of the upload. It should be called after Done is closed.
+func (u *Upload) Err() error {
+	return u.err
+}
+
+// Receiver is an sFTP server accepting only expected uploads.
+type Receiver struct {
+	options  Options
+	username string
+	password string
+	config   *ssh.ServerConfig
+
+	mu       sync.Mutex
+	uploads  []*Upload
+	pending  int
+	conns    map[net.Conn]struct{}
+	listener net.Listener
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns Receiver with random credentials and host key.
+func New(options Options) (*Receiver, error) {
+	if options.Port == 0 {
+		options.Port = DefaultPort
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	username, err := RandomString(options.UsernameLength)
+	if err != nil {
+		return nil, err
+	}
+	password, err := RandomString(options.PasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		return nil, err
+	}
+	r := &Receiver{
+		options:  options,
+		username: username,
+		password: password,
+		conns:    make(map[net.Conn]struct{}),
+		done:     make(chan struct{}),
+	}
+	r.config = &ssh.ServerConfig{
+		MaxAuthTries:     3,
+		PasswordCallback: r.checkPassword,
+	}
+	r.config.AddHostKey(signer)
+	return r, nil
+}
+
+func (r *Receiver) Username() string {
+	return r.username
+}
+
+func (r *Receiver) Password() string {
+	return r.password
+}
+
+// Port returns port Receiver listens on.
+func (r *Receiver) Port() int {
+	return r.options.Port
+}
+
+func (r *Receiver) checkPassword(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	usernameOk := subtle.ConstantTimeCompare([]byte(conn.User()), []byte(r.username)) == 1
+	passwordOk := subtle.ConstantTimeCompare(password, []byte(r.password)) == 1
+	if !usernameOk || !passwordOk {
+		return nil, fmt.Errorf("wrong credentials for %s", conn.User())
+	}
+	return nil, nil
+}
+
+// Expect registers upload of the file name from client (IP address or DNS
+// name) and returns it. Received file is saved to localPath. Expect should
+// be called before Start.
+func (r *Receiver) Expect(client, name, localPath string) (*Upload, error) {
+	client = strings.Trim(client, "[]")
+	var clients []net.IP
+	if ip := net.ParseIP(client); ip != nil {
+		clients = append(clients, ip)
+	} else {
+		ips, err := net.LookupIP(client)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrResolve, client, err)
+		}
+		clients = ips
+	}
+	u := &Upload{
+		name:      path.Clean("/" + name),
+		localPath: localPath,
+		clients:   clients,
+		done:      make(chan struct{}),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.listener != nil {
+		return nil, ErrStarted
+	}
+	r.uploads = append(r.uploads, u)
+	r.pending++
+	return u, nil
+}
+
+// Arm starts timeout of the upload. It should be called when client is asked
+// to upload the file, so uploads queued behind others do not time out before
+// they begin. Upload not finished within timeout fails with ErrTimeout.
+func (r *Receiver) Arm(u *Upload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.finished || u.timer != nil {
+		return
+	}
+	u.timer = time.AfterFunc(r.options.Timeout, func() {
+		log.Printf("sFTP receiver: %s: %v", u.name, ErrTimeout)
+		r.finish(u, ErrTimeout)
+	})
+}
+
+// Start listens for connections. Receiver stops when all expected uploads
+// are finished or failed or ctx is done.
+func (r *Receiver) Start(ctx context.Context) error {
+	address := net.JoinHostPort(r.options.Address, strconv.Itoa(r.options.Port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.listener = listener
+	r.mu.Unlock()
+	log.Printf("sFTP receiver listens on %s", address)
+	go r.serve()
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.stop(ctx.Err())
+		case <-r.done:
+		}
+	}()
+	return nil
+}
+
+// Close stops Receiver and closes all connections. Unfinished uploads fail
+// with ErrIncomplete.
+func (r *Receiver) Close() error {
+	r.stop(ErrIncomplete)
+	r.closeConns()
+	return nil
+}
+
+func (r *Receiver) closeConns() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for conn := range r.conns {
+		conn.Close()
+	}
+}
+
+// stop closes listener and fails unfinished uploads with err. If err is not
+// nil, connections are closed. Otherwise clients can finish their sessions.
+func (r *Receiver) stop(err error) {
+	r.stopOnce.Do(func() {
+		if err != nil {
+			r.closeConns()
+		}
+		r.mu.Lock()
+		if r.listener != nil {
+			r.listener.Close()
+		}
+		uploads := slices.Clone(r.uploads)
+		r.mu.Unlock()
+		for _, u := range uploads {
+			r.finish(u, err)
+		}
+		close(r.done)
+		log.Print("sFTP receiver stopped")
+	})
+}
+
+// finish sets result of the upload. Receiver is stopped after last upload.
+func (r *Receiver) finish(u *Upload, err error) {
+	r.mu.Lock()
+	if u.finished {
+		r.mu.Unlock()
+		return
+	}
+	u.finished = true
+	u.err = err
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+	close(u.done)
+	r.pending--
+	last := r.pending == 0
+	r.mu.Unlock()
+	if last {
+		go r.stop(nil)
+	}
+}
+
+func (r *Receiver) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+// allowed checks whether any unfinished upload expects ip.
+func (r *Receiver) allowed(ip net.IP) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.uploads {
+		if !u.finished && slices.ContainsFunc(u.clients, ip.Equal) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Receiver) track(conn net.Conn, add bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if add {
+		r.conns[conn] = struct{}{}
+	} else {
+		delete(r.conns, conn)
+	}
+}
+
+func (r *Receiver) handle(conn net.Conn) {
+	defer conn.Close()
+	ip := conn.RemoteAddr().(*net.TCPAddr).IP
+	if !r.allowed(ip) {
+		log.Printf("sFTP receiver: rejected connection from %v", ip)
+		return
+	}
+	r.track(conn, true)
+	defer r.track(conn, false)
+	_, channels, requests, err := ssh.NewServerConn(conn, r.config)
+	if err != nil {
+		log.Printf("sFTP receiver: %v: %v", ip, err)
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("sFTP receiver: %v: %v", ip, err)
+			return
+		}
+		go r.session(ip, channel, requests)
+	}
+}
+
+// session serves sftp subsystem. All other requests are rejected.
+func (r *Receiver) session(ip net.IP, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	for req := range requests {
+		if !isSFTP(req) {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+		server := sftp.NewRequestServer(channel, r.handlers(ip))
+		if err := server.Serve(); err != nil && err != io.EOF {
+			log.Printf("sFTP receiver: %v: %v", ip, err)
+		}
+		server.Close()
+		return
+	}
+}
+
+func isSFTP(req *ssh.Request) bool {
+	if req.Type != "subsystem" || len(req.Payload) < 4 {
+		return false
+	}
+	length := binary.BigEndian.Uint32(req.Payload)
+	return uint64(length) == uint64(len(req.Payload)-4) && string(req.Payload[4:]) == "sftp"
+}
+
+// find returns upload of the file name expected from ip.
+func (r *Receiver) find(ip net.IP, name string) *Upload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.uploads {
+		if u.name == name && slices.ContainsFunc(u.clients, ip.Equal) {
+			return u
+		}
+	}
+	return nil
+}
+
+// take marks upload as started. It returns nil if upload is not expected or already started.
+func (r *Receiver) take(ip net.IP, name string) *Upload {
+	u := r.find(ip, name)
+	if u == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.started || u.finished {
+		return nil
+	}
+	u.started = true
+	return u
+}
+
+func (r *Receiver) handlers(ip net.IP) sftp.Handlers {
+	h := &handler{receiver: r, ip: ip}
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// handler allows only upload of the expected file.
+type handler struct {
+	receiver *Receiver
+	ip       net.IP
+}
+
+func (h *handler) Fileread(*sftp.Request) (io.ReaderAt, error) {
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+func (h *handler) Filewrite(req *sftp.Request) (io.WriterAt, error) {
+	u := h.receiver.take(h.ip, req.Filepath)
+	if u == nil {
+		log.Printf("sFTP receiver: %v: denied upload to %s", h.ip, req.Filepath)
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	log.Printf("sFTP receiver: %v: upload %s", h.ip, req.Filepath)
+	file, err := os.OpenFile(u.localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		h.receiver.finish(u, err)
+		return nil, sftp.ErrSSHFxFailure
+	}
+	return &uploadFile{File: file, receiver: h.receiver, upload: u}, nil
+}
+
+// Filecmd allows only setting attributes of the uploaded file. They are ignored.
+func (h *handler) Filecmd(req *sftp.Request) error {
+	if req.Method == "Setstat" && h.receiver.find(h.ip, req.Filepath) != nil {
+		return nil
+	}
+	return sftp.ErrSSHFxPermissionDenied
+}
+
+// Filelist allows only Stat of the expected file.
+func (h *handler) Filelist(req *sftp.Request) (sftp.ListerAt, error) {
+	if req.Method != "Stat" {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	u := h.receiver.find(h.ip, req.Filepath)
+	if u == nil {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	info, err := os.Stat(u.localPath)
+	if err != nil {
+		return nil, sftp.ErrSSHFxNoSuchFile
+	}
+	return listerAt{info}, nil
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(f []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(f, l[offset:])
+	if n < len(f) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// uploadFile finishes upload when file is closed.
+type uploadFile struct {
+	*os.File
+	receiver *Receiver
+	upload   *Upload
+	err      error
+}
+
+var _ sftp.TransferError = &uploadFile{}
+
+// TransferError is called if connection is lost during upload.
+func (f *uploadFile) TransferError(err error) {
+	f.err = fmt.Errorf("%w: %w", ErrIncomplete, err)
+}
+
+func (f *uploadFile) Close() error {
+	err := f.File.Close()
+	if f.err != nil {
+		err = f.err
+	}
+	f.receiver.finish(f.upload, err)
+	return err
+}
+
+// RandomString returns string of n random letters and digits generated by crypto/rand.
+func RandomString(n int) (string, error) {
+	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	limit := big.NewInt(int64(len(letterBytes)))
+	b := make([]byte, n)
+	for i := range b {
+		index, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		b[i] = letterBytes[index.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,176 @@
+package receiver
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestReceiver(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "backup.gz")
+	r, err := New(Options{
+		Address:        "127.0.0.1",
+		Port:           freePort(t),
+		Timeout:        time.Minute,
+		UsernameLength: 16,
+		PasswordLength: 16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := r.Expect("127.0.0.1", "/tmp/backup.gz", localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port()))
+	config := &ssh.ClientConfig{
+		User:            r.Username(),
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+	config.Auth = []ssh.AuthMethod{ssh.Password("wrong")}
+	if _, err := ssh.Dial("tcp", address, config); err == nil {
+		t.Fatal("wrong password accepted")
+	}
+	config.Auth = []ssh.AuthMethod{ssh.Password(r.Password())}
+	conn, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Create("/tmp/other.gz"); err == nil {
+		t.Error("upload with unexpected name accepted")
+	}
+	if _, err := client.Open("/etc/passwd"); err == nil {
+		t.Error("download accepted")
+	}
+	f, err := client.Create("/tmp/backup.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("backup")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Create("/tmp/backup.gz"); err == nil {
+		t.Error("second upload accepted")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-upload.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("upload is not finished")
+	}
+	if err := upload.Err(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "backup" {
+		t.Errorf("expected %q, got %q", "backup", data)
+	}
+	select {
+	case <-r.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("receiver is not stopped after upload")
+	}
+}
+
+func TestReceiverRejectsClient(t *testing.T) {
+	r, err := New(Options{Address: "127.0.0.1", Port: freePort(t), UsernameLength: 8, PasswordLength: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := r.Expect("192.0.2.1", "backup.gz", filepath.Join(t.TempDir(), "backup.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ClientConfig{
+		User:            r.Username(),
+		Auth:            []ssh.AuthMethod{ssh.Password(r.Password())},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+	if _, err := ssh.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port())), config); err == nil {
+		t.Error("connection from unexpected client accepted")
+	}
+	r.Close()
+	<-upload.Done()
+	if upload.Err() != ErrIncomplete {
+		t.Errorf("expected %v, got %v", ErrIncomplete, upload.Err())
+	}
+}
+
+func TestReceiverTimeout(t *testing.T) {
+	r, err := New(Options{Address: "127.0.0.1", Port: freePort(t), Timeout: 100 * time.Millisecond, UsernameLength: 8, PasswordLength: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	armed, err := r.Expect("127.0.0.1", "first.gz", filepath.Join(dir, "first.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := r.Expect("127.0.0.1", "second.gz", filepath.Join(dir, "second.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Arm(armed)
+	select {
+	case <-armed.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("armed upload is not timed out")
+	}
+	if armed.Err() != ErrTimeout {
+		t.Errorf("expected %v, got %v", ErrTimeout, armed.Err())
+	}
+	select {
+	case <-queued.Done():
+		t.Fatalf("upload that is not armed finished: %v", queued.Err())
+	case <-time.After(300 * time.Millisecond):
+	}
+	r.Arm(queued)
+	select {
+	case <-queued.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("queued upload is not timed out")
+	}
+	if queued.Err() != ErrTimeout {
+		t.Errorf("expected %v, got %v", ErrTimeout, queued.Err())
+	}
+}
@@ -0,0 +1,179 @@
+package smsbackup
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log"
+	"time"
+)
+
+// Chain statuses of the certificate
+const (
+	ChainOK              = "ok"
+	ChainSelfSigned      = "self-signed"
+	ChainMissingIssuer   = "missing-issuer"
+	ChainExpired         = "expired"
+	ChainExpiredIssuer   = "expired-issuer"
+	ChainNameConstraints = "name-constraints"
+	ChainInvalid         = "invalid"
+	ChainUnparsable      = "unparsable"
+)
+
+// maxChainLength limits issuer lookup in case of loops.
+const maxChainLength = 10
+
+// Chain is an issuing chain of the certificate built from CA certificates
+// of the same backup.
+type Chain struct {
+	Status string
+	// Path is a list of names from the certificate to the root. SMS names are
+	// used for CA certificates
+	Path []string
+	// RootExpiry is zero if chain does not end with self-signed certificate
+	RootExpiry time.Time
+}
+
+// caStore holds CA certificates of the SMS.
+type caStore struct {
+	certs         []*x509.Certificate
+	names         map[*x509.Certificate]string
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+}
+
+func newCAStore() *caStore {
+	return &caStore{
+		names:         make(map[*x509.Certificate]string),
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+	}
+}
+
+// add adds PEM encoded CA certificate. Unparsable certificates are skipped.
+func (s *caStore) add(name string, data []byte) {
+	cert, err := parsePEM(data)
+	if err != nil {
+		log.Printf("CA certificate %s: %v", name, err)
+		return
+	}
+	s.certs = append(s.certs, cert)
+	s.names[cert] = name
+	if isSelfSigned(cert) {
+		s.roots.AddCert(cert)
+	} else {
+		s.intermediates.AddCert(cert)
+	}
+}
+
+// parsePEM parses PEM encoded certificate.
+func parsePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrFailedToParsePEMCertificate
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// isSelfSigned checks certificate signature by its own key. CheckSignatureFrom
+// is not used as it requires certificate to be CA.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// issuer returns CA certificate that signed cert or nil.
+func (s *caStore) issuer(cert *x509.Certificate) *x509.Certificate {
+	for _, ca := range s.certs {
+		if bytes.Equal(cert.RawIssuer, ca.RawSubject) && cert.CheckSignatureFrom(ca) == nil {
+			return ca
+		}
+	}
+	return nil
+}
+
+// chain builds and validates issuing chain of the certificate.
+func (s *caStore) chain(name string, data []byte) Chain {
+	cert, err := parsePEM(data)
+	if err != nil {
+		return Chain{Status: ChainUnparsable}
+	}
+	path := []*x509.Certificate{cert}
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: s.intermediates,
+		CurrentTime:   now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err == nil {
+		path = chains[0]
+	} else {
+		for c := cert; len(path) < maxChainLength && !isSelfSigned(c); {
+			c = s.issuer(c)
+			if c == nil {
+				break
+			}
+			path = append(path, c)
+		}
+	}
+	result := Chain{Status: chainStatus(cert, path, err)}
+	for i, c := range path {
+		if i == 0 {
+			result.Path = append(result.Path, name)
+			continue
+		}
+		result.Path = append(result.Path, s.names[c])
+	}
+	if root := path[len(path)-1]; isSelfSigned(root) {
+		result.RootExpiry = root.NotAfter
+	}
+	return result
+}
+
+// chainStatus converts verification error into chain status.
+func chainStatus(cert *x509.Certificate, path []*x509.Certificate, err error) string {
+	if err == nil {
+		return ChainOK
+	}
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) {
+		switch invalid.Reason {
+		case x509.Expired:
+			if invalid.Cert == cert {
+				return ChainExpired
+			}
+			return ChainExpiredIssuer
+		case x509.CANotAuthorizedForThisName, x509.TooManyConstraints:
+			return ChainNameConstraints
+		}
+		return ChainInvalid
+	}
+	var unknown x509.UnknownAuthorityError
+	if errors.As(err, &unknown) {
+		root := path[len(path)-1]
+		if len(path) == 1 && isSelfSigned(root) {
+			return ChainSelfSigned
+		}
+		if isSelfSigned(root) {
+			// Whole chain is present, so some certificate of it is not valid
+			return chainExpiry(path)
+		}
+		return ChainMissingIssuer
+	}
+	return ChainInvalid
+}
+
+// chainExpiry returns expiry status of the chain certificates.
+func chainExpiry(path []*x509.Certificate) string {
+	t := now()
+	for i, c := range path {
+		if t.Before(c.NotBefore) || t.After(c.NotAfter) {
+			if i == 0 {
+				return ChainExpired
+			}
+			return ChainExpiredIssuer
+		}
+	}
+	return ChainInvalid
+}
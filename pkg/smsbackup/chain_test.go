@@ -0,0 +1,112 @@
+package smsbackup
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// issue creates certificate signed by parent or self-signed if parent is nil.
+func issue(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func caTemplate(name string, notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+}
+
+func leafTemplate(notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "www.example.com"},
+		DNSNames:  []string{"www.example.com"},
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+}
+
+func TestChain(t *testing.T) {
+	n := time.Now()
+	past, future := n.Add(-time.Hour), n.Add(24*time.Hour)
+	root := issue(t, caTemplate("root", past, future), nil)
+	intermediate := issue(t, caTemplate("intermediate", past, future), root)
+	leaf := issue(t, leafTemplate(past, future), intermediate)
+	expiredLeaf := issue(t, leafTemplate(past.Add(-time.Hour), past), intermediate)
+	expiredIntermediate := issue(t, caTemplate("expired", past.Add(-time.Hour), past), root)
+	leafOfExpired := issue(t, leafTemplate(past.Add(-time.Hour), future), expiredIntermediate)
+	constrainedTemplate := caTemplate("constrained", past, future)
+	constrainedTemplate.PermittedDNSDomains = []string{"example.org"}
+	constrained := issue(t, constrainedTemplate, root)
+	leafOfConstrained := issue(t, leafTemplate(past, future), constrained)
+	selfSigned := issue(t, leafTemplate(past, future), nil)
+
+	testCases := []struct {
+		name   string
+		cas    []*testCert
+		leaf   *testCert
+		status string
+		path   []string
+	}{
+		{"ok", []*testCert{root, intermediate}, leaf, ChainOK, []string{"leaf", "intermediate", "root"}},
+		{"no root", []*testCert{intermediate}, leaf, ChainMissingIssuer, []string{"leaf", "intermediate"}},
+		{"no intermediate", []*testCert{root}, leaf, ChainMissingIssuer, []string{"leaf"}},
+		{"expired", []*testCert{root, intermediate}, expiredLeaf, ChainExpired, []string{"leaf", "intermediate", "root"}},
+		{"expired issuer", []*testCert{root, expiredIntermediate}, leafOfExpired, ChainExpiredIssuer, []string{"leaf", "expired", "root"}},
+		{"name constraints", []*testCert{root, constrained}, leafOfConstrained, ChainNameConstraints, []string{"leaf", "constrained", "root"}},
+		{"self-signed", []*testCert{root}, selfSigned, ChainSelfSigned, []string{"leaf"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newCAStore()
+			for _, ca := range tc.cas {
+				store.add(ca.cert.Subject.CommonName, ca.pem)
+			}
+			chain := store.chain("leaf", tc.leaf.pem)
+			if chain.Status != tc.status {
+				t.Errorf("expected status %s, got %s", tc.status, chain.Status)
+			}
+			if !slices.Equal(chain.Path, tc.path) {
+				t.Errorf("expected path %v, got %v", tc.path, chain.Path)
+			}
+			if tc.path[len(tc.path)-1] == "root" && !chain.RootExpiry.Equal(root.cert.NotAfter) {
+				t.Errorf("expected root expiry %v, got %v", root.cert.NotAfter, chain.RootExpiry)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package smsbackup
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/mpkondrashin/certlist/pkg/model"
+)
+
+// Device is Tipping Point IPS.
+type Device struct {
+	ShortID uint
+	Name    string
+	IP      string
+	TOS     string
+}
+
+// SSLServer is SSL server proxy configured in SMS.
+type SSLServer struct {
+	ID   string
+	Name string
+}
+
+// ClientProxy is SSL client proxy configured in SMS. It uses the certificate
+// as CA to inspect outbound SSL traffic.
+type ClientProxy struct {
+	ID   string
+	Name string
+	// MinKeyLength is 0 if not set
+	MinKeyLength int
+	// BlockInvalid - block connections with invalid server certificates
+	BlockInvalid bool
+	// BlockExpired - block connections with expired server certificates
+	BlockExpired bool
+	// Decryptions are SSL client decryption rules of the profiles using
+	// the proxy
+	Decryptions []*Decryption
+}
+
+// Decryption is SSL client decryption rule configured in SMS.
+type Decryption struct {
+	ID   string
+	Name string
+	// Domains are domain names the rule applies to
+	Domains []string
+	// Categories are names of the URL categories the rule applies to
+	Categories []string
+	// NamedObjects are names of the network objects the rule applies to
+	NamedObjects []string
+}
+
+// Port of the SSL server or SSL client proxy. End is 0 for single port.
+type Port struct {
+	Protocol string
+	Start    uint
+	End      uint
+}
+
+// Deployment is a use of the certificate by SSL server or SSL client proxy
+// on device. Device is nil if no profile using SSL server or SSL client proxy
+// is installed on any device. Both SSLServer and ClientProxy are nil if
+// certificate is installed on device, but is not used there.
+type Deployment struct {
+	Device      *Device
+	SSLServer   *SSLServer
+	ClientProxy *ClientProxy
+	Ports       []Port
+}
+
+// Certificate is a named certificate with its deployments.
+type Certificate struct {
+	ID          int
+	Name        string
+	Thumbprint  string
+	PEM         []byte
+	Deployments []Deployment
+	Chain       Chain
+}
+
+// appendUnique appends v to s if s does not contain it.
+func appendUnique[T comparable](s []T, v T) []T {
+	if slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
+
+// LoadCertificates returns certificates with private keys and their
+// deployments. SSL servers and SSL client proxies are attributed to devices
+// through the profiles referring to them and the virtual segments these
+// profiles are installed on. Devices certificate is installed on but not used
+// by any of them are listed as deployments without SSL server and SSL client
+// proxy. Issuing chains are built from CA certificates of the same SMS.
+func LoadCertificates(ctx context.Context, db *sql.DB) ([]*Certificate, error) {
+	devices := make(map[uint]*Device)
+	for device, err := range model.RangeTptDevice(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		devices[device.ShortID] = &Device{
+			ShortID: device.ShortID,
+			Name:    device.DisplayName.String,
+			IP:      device.IPAddress.String,
+			TOS:     device.SoftwareVersion.String,
+		}
+	}
+	certDevices := make(map[int][]uint)
+	for dc, err := range model.RangeDeviceCertificate(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		certDevices[dc.NamedCertificateID] = appendUnique(certDevices[dc.NamedCertificateID], dc.DeviceShortID)
+	}
+	servers := make(map[string]*SSLServer)
+	for server, err := range model.RangeSslServer(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		servers[server.SslServerID] = &SSLServer{
+			ID:   server.SslServerID,
+			Name: server.Name,
+		}
+	}
+	certServers := make(map[int][]string)
+	for ssc, err := range model.RangeSslServerCertificates(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		certServers[ssc.NamedCertificateID] = appendUnique(certServers[ssc.NamedCertificateID], ssc.SslServerID)
+	}
+	serverPorts := make(map[string][]Port)
+	for port, err := range model.RangeSslServerPort(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		p := newPort(port.ProtocolType, port.StartPort, port.EndPort)
+		serverPorts[port.SslServerID] = appendUnique(serverPorts[port.SslServerID], p)
+	}
+	certProxies := make(map[int][]*ClientProxy)
+	for proxy, err := range model.RangeSslClientProxy(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		certProxies[proxy.NamedCertificateID] = append(certProxies[proxy.NamedCertificateID], &ClientProxy{
+			ID:           proxy.SslClientProxyID,
+			Name:         proxy.Name,
+			MinKeyLength: int(proxy.MinKeyLength.Int32),
+			BlockInvalid: proxy.BlockInvCertEnabled != 0,
+			BlockExpired: proxy.BlockExpCertEnabled != 0,
+		})
+	}
+	proxyPorts := make(map[string][]Port)
+	for port, err := range model.RangeSslClientProxyPort(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		p := newPort(port.ProtocolType, port.StartPort, port.EndPort)
+		proxyPorts[port.SslClientProxyID] = appendUnique(proxyPorts[port.SslClientProxyID], p)
+	}
+	profileDevices, err := loadProfileDevices(db, devices)
+	if err != nil {
+		return nil, err
+	}
+	profileDecryptions, err := loadProfileDecryptions(db)
+	if err != nil {
+		return nil, err
+	}
+	serverDevices := make(map[string][]*Device)
+	proxyDevices := make(map[string][]*Device)
+	proxyDecryptions := make(map[string][]*Decryption)
+	for policy, err := range model.RangePolicy(db, "SSL_SERVER_ID IS NOT NULL") {
+		if err != nil {
+			return nil, err
+		}
+		id := policy.SslServerID.String
+		for _, device := range profileDevices[policy.ProfileID] {
+			serverDevices[id] = appendUnique(serverDevices[id], device)
+		}
+	}
+	for policy, err := range model.RangePolicy(db, "SSL_CLIENT_PROXY_ID IS NOT NULL") {
+		if err != nil {
+			return nil, err
+		}
+		id := policy.SslClientProxyID.String
+		for _, device := range profileDevices[policy.ProfileID] {
+			proxyDevices[id] = appendUnique(proxyDevices[id], device)
+		}
+		for _, decryption := range profileDecryptions[policy.ProfileID] {
+			proxyDecryptions[id] = appendUnique(proxyDecryptions[id], decryption)
+		}
+	}
+	for _, proxies := range certProxies {
+		for _, proxy := range proxies {
+			proxy.Decryptions = proxyDecryptions[proxy.ID]
+		}
+	}
+	cas := newCAStore()
+	for nc, err := range model.RangeNamedCertificate(db, "CA=1") {
+		if err != nil {
+			return nil, err
+		}
+		cas.add(nc.Name, nc.CertBytes)
+	}
+	var certificates []*Certificate
+	for nc, err := range model.RangeNamedCertificate(db, "PRIVATE_KEY_EXPECTED=1") {
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cert := &Certificate{
+			ID:         nc.ID,
+			Name:       nc.Name,
+			Thumbprint: nc.Thumbprint,
+			PEM:        nc.CertBytes,
+			Chain:      cas.chain(nc.Name, nc.CertBytes),
+		}
+		var certDeviceList []*Device
+		for _, shortID := range certDevices[nc.ID] {
+			certDeviceList = append(certDeviceList, deviceByID(devices, shortID))
+		}
+		var uses []Deployment
+		for _, id := range certServers[nc.ID] {
+			if server, ok := servers[id]; ok {
+				uses = append(uses, deviceUses(serverDevices[id], Deployment{SSLServer: server, Ports: serverPorts[id]})...)
+			}
+		}
+		for _, proxy := range certProxies[nc.ID] {
+			uses = append(uses, deviceUses(proxyDevices[proxy.ID], Deployment{ClientProxy: proxy, Ports: proxyPorts[proxy.ID]})...)
+		}
+		cert.Deployments = deployments(certDeviceList, uses)
+		certificates = append(certificates, cert)
+	}
+	return certificates, nil
+}
+
+// newPort returns port omitting end equal to start.
+func newPort(protocol string, start uint, end sql.NullInt32) Port {
+	p := Port{
+		Protocol: protocol,
+		Start:    start,
+	}
+	if end.Valid && uint(end.Int32) != start {
+		p.End = uint(end.Int32)
+	}
+	return p
+}
+
+// deviceByID returns device with given short ID. Device missing in TPT_DEVICE
+// has only ShortID set and is added to devices.
+func deviceByID(devices map[uint]*Device, shortID uint) *Device {
+	d, ok := devices[shortID]
+	if !ok {
+		d = &Device{ShortID: shortID}
+		devices[shortID] = d
+	}
+	return d
+}
+
+// loadProfileDevices returns devices having virtual segments each profile is
+// installed on.
+func loadProfileDevices(db *sql.DB, devices map[uint]*Device) (map[string][]*Device, error) {
+	segmentDevices := make(map[uint]*Device)
+	for segment, err := range model.RangeVirtualSegment(db, "DEVICE_SHORT_ID IS NOT NULL") {
+		if err != nil {
+			return nil, err
+		}
+		segmentDevices[segment.ID] = deviceByID(devices, uint(segment.DeviceShortID.Int32))
+	}
+	profileDevices := make(map[string][]*Device)
+	for install, err := range model.RangeProfileInstallInventory(db, "VIRTUAL_SEGMENT_ID IS NOT NULL") {
+		if err != nil {
+			return nil, err
+		}
+		d, ok := segmentDevices[uint(install.VirtualSegmentID.Int32)]
+		if !ok {
+			continue
+		}
+		profileDevices[install.ProfileID] = appendUnique(profileDevices[install.ProfileID], d)
+	}
+	return profileDevices, nil
+}
+
+// loadProfileDecryptions returns SSL client decryption rules of each profile.
+func loadProfileDecryptions(db *sql.DB) (map[string][]*Decryption, error) {
+	decryptions := make(map[string]*Decryption)
+	for decrypt, err := range model.RangeSslClientDecrypt(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		decryptions[decrypt.SslClientDecryptID] = &Decryption{
+			ID:   decrypt.SslClientDecryptID,
+			Name: decrypt.Name,
+		}
+	}
+	for domain, err := range model.RangeSslClientDecryptDomain(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := decryptions[domain.SslClientDecryptID]; ok {
+			d.Domains = appendUnique(d.Domains, domain.DomainName)
+		}
+	}
+	categories := make(map[uint]string)
+	for category, err := range model.RangeSslCategory(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		categories[category.SslCategoryID] = category.Name.String
+	}
+	for category, err := range model.RangeSslClientDecryptCat(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := decryptions[category.SslClientDecryptID]; ok {
+			d.Categories = appendUnique(d.Categories, categories[category.SslCategoryID])
+		}
+	}
+	for object, err := range model.RangeSslClientDecryptNamedObj(db, "") {
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := decryptions[object.SslClientDecryptID]; ok {
+			d.NamedObjects = appendUnique(d.NamedObjects, object.Name.String)
+		}
+	}
+	profileDecryptions := make(map[string][]*Decryption)
+	for policy, err := range model.RangePolicy(db, "SSL_CLIENT_DECRYPT_ID IS NOT NULL") {
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := decryptions[policy.SslClientDecryptID.String]; ok {
+			profileDecryptions[policy.ProfileID] = appendUnique(profileDecryptions[policy.ProfileID], d)
+		}
+	}
+	return profileDecryptions, nil
+}
+
+// deviceUses returns use of the certificate on each of the devices or use
+// itself without device if devices are not known.
+func deviceUses(devices []*Device, use Deployment) []Deployment {
+	if len(devices) == 0 {
+		return []Deployment{use}
+	}
+	result := make([]Deployment, len(devices))
+	for i, d := range devices {
+		result[i] = use
+		result[i].Device = d
+	}
+	return result
+}
+
+// deployments returns uses of the certificate followed by devices it is
+// installed on but not used on by any SSL server or SSL client proxy.
+func deployments(devices []*Device, uses []Deployment) []Deployment {
+	result := slices.Clip(uses)
+	for _, d := range devices {
+		if !slices.ContainsFunc(uses, func(use Deployment) bool { return use.Device == d }) {
+			result = append(result, Deployment{Device: d})
+		}
+	}
+	return result
+}
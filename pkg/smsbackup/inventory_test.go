@@ -0,0 +1,187 @@
+package smsbackup
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mpkondrashin/certlist/pkg/dumpreader"
+)
+
+func selfSigned(t *testing.T, name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestDeployments(t *testing.T) {
+	ips1 := &Device{ShortID: 1, Name: "ips1"}
+	ips2 := &Device{ShortID: 2, Name: "ips2"}
+	web := Deployment{SSLServer: &SSLServer{ID: "a", Name: "web"}, Ports: []Port{{Protocol: "TCP", Start: 443}}}
+	outbound := Deployment{ClientProxy: &ClientProxy{ID: "b", Name: "outbound"}}
+	testCases := []struct {
+		name    string
+		devices []*Device
+		uses    []Deployment
+		count   int
+	}{
+		{"used on each device", []*Device{ips1, ips2}, append(deviceUses([]*Device{ips1}, web), deviceUses([]*Device{ips2}, outbound)...), 2},
+		{"used on one device", []*Device{ips1, ips2}, deviceUses([]*Device{ips1}, web), 2},
+		{"used on unknown devices", []*Device{ips1}, deviceUses(nil, web), 2},
+		{"no uses", []*Device{ips1, ips2}, nil, 2},
+		{"no devices", nil, deviceUses([]*Device{ips1, ips2}, web), 2},
+		{"unused", nil, nil, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := deployments(tc.devices, tc.uses)
+			if len(result) != tc.count {
+				t.Fatalf("expected %d deployments, got %d", tc.count, len(result))
+			}
+			for _, d := range result {
+				if d.SSLServer != nil && len(d.Ports) != 1 {
+					t.Errorf("expected ports of %s, got %v", d.SSLServer.Name, d.Ports)
+				}
+			}
+			for _, device := range tc.devices {
+				if !slices.ContainsFunc(result, func(d Deployment) bool { return d.Device == device }) {
+					t.Errorf("%s is missing", device.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCertificates(t *testing.T) {
+	f, err := os.Open("testdata/inventory.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dump, err := dumpreader.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dump.DB()
+	defer db.Close()
+	certs, err := LoadCertificates(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(certs))
+	}
+	report, err := Render(certs, ReportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ReportLine{
+		{IpsName: "ips1", ManagmentIP: "10.0.0.1", Tos: "6.0.0", SSLServerProxies: "web", StartPort: "443"},
+		{IpsName: "ips2", ManagmentIP: "10.0.0.2", Tos: "6.1.0", SSLServerProxies: "mail", StartPort: "465"},
+		{SSLClientProxies: "outbound", BlockInvalidCert: "false", BlockExpiredCert: "true", SSLClientDecryptions: "bank"},
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(expected), len(report), report)
+	}
+	for i, e := range expected {
+		r := report[i]
+		if r.CertName != "inventory" || r.IpsName != e.IpsName || r.ManagmentIP != e.ManagmentIP || r.Tos != e.Tos ||
+			r.SSLServerProxies != e.SSLServerProxies || r.StartPort != e.StartPort ||
+			r.SSLClientProxies != e.SSLClientProxies || r.BlockInvalidCert != e.BlockInvalidCert ||
+			r.BlockExpiredCert != e.BlockExpiredCert || r.SSLClientDecryptions != e.SSLClientDecryptions {
+			t.Errorf("line %d: expected %+v, got %+v", i, e, r)
+		}
+	}
+	decryptions := report[2].Record().SSLClientDecryptions
+	expectedDecryptions := []RecordDecryption{{Name: "bank", Domains: []string{"bank.example.com"},
+		Categories: []string{"Finance"}, NamedObjects: []string{"branch"}}}
+	if !reflect.DeepEqual(decryptions, expectedDecryptions) {
+		t.Errorf("expected %+v, got %+v", expectedDecryptions, decryptions)
+	}
+}
+
+func TestRenderLayouts(t *testing.T) {
+	ips1 := &Device{ShortID: 1, Name: "ips1", IP: "10.0.0.1"}
+	ips2 := &Device{ShortID: 2, Name: "ips2", IP: "10.0.0.2"}
+	web := Deployment{SSLServer: &SSLServer{ID: "a", Name: "web"}, Ports: []Port{{Protocol: "TCP", Start: 443}}}
+	mail := Deployment{SSLServer: &SSLServer{ID: "b", Name: "mail"}, Ports: []Port{{Protocol: "TCP", Start: 993}}}
+	outbound := Deployment{ClientProxy: &ClientProxy{ID: "c", Name: "outbound", MinKeyLength: 2048, BlockExpired: true}}
+	certs := []*Certificate{
+		{Name: "shared", PEM: selfSigned(t, "shared"), Deployments: deployments([]*Device{ips1, ips2},
+			append(deviceUses([]*Device{ips1, ips2}, web), deviceUses([]*Device{ips1, ips2}, mail)...))},
+		{Name: "unused", PEM: selfSigned(t, "unused")},
+		{Name: "ca", PEM: selfSigned(t, "ca"), Deployments: deployments([]*Device{ips1}, deviceUses([]*Device{ips1}, outbound))},
+	}
+	ca := ReportLine{CertName: "ca", IpsName: "ips1", ManagmentIP: "10.0.0.1", SSLClientProxies: "outbound",
+		MinKeyLength: "2048", BlockInvalidCert: "false", BlockExpiredCert: "true"}
+	testCases := []struct {
+		layout   string
+		expected []ReportLine
+	}{
+		{LayoutDevice, []ReportLine{
+			{CertName: "shared", IpsName: "ips1", ManagmentIP: "10.0.0.1", SSLServerProxies: "web,mail", StartPort: "443,993"},
+			{CertName: "shared", IpsName: "ips2", ManagmentIP: "10.0.0.2", SSLServerProxies: "web,mail", StartPort: "443,993"},
+			{CertName: "unused"},
+			ca,
+		}},
+		{LayoutFlat, []ReportLine{
+			{CertName: "shared", IpsName: "ips1", ManagmentIP: "10.0.0.1", SSLServerProxies: "web", StartPort: "443"},
+			{CertName: "shared", IpsName: "ips2", ManagmentIP: "10.0.0.2", SSLServerProxies: "web", StartPort: "443"},
+			{CertName: "shared", IpsName: "ips1", ManagmentIP: "10.0.0.1", SSLServerProxies: "mail", StartPort: "993"},
+			{CertName: "shared", IpsName: "ips2", ManagmentIP: "10.0.0.2", SSLServerProxies: "mail", StartPort: "993"},
+			{CertName: "unused"},
+			ca,
+		}},
+		{LayoutGrouped, []ReportLine{
+			{CertName: "shared", IpsName: "ips1,ips2", ManagmentIP: "10.0.0.1,10.0.0.2", SSLServerProxies: "web,mail", StartPort: "443,993"},
+			{CertName: "unused"},
+			ca,
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.layout, func(t *testing.T) {
+			report, err := Render(certs, ReportOptions{Layout: tc.layout})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report) != len(tc.expected) {
+				t.Fatalf("expected %d lines, got %d", len(tc.expected), len(report))
+			}
+			for i, e := range tc.expected {
+				r := report[i]
+				if r.CertName != e.CertName || r.IpsName != e.IpsName || r.ManagmentIP != e.ManagmentIP ||
+					r.SSLServerProxies != e.SSLServerProxies || r.StartPort != e.StartPort ||
+					r.SSLClientProxies != e.SSLClientProxies || r.MinKeyLength != e.MinKeyLength ||
+					r.BlockInvalidCert != e.BlockInvalidCert || r.BlockExpiredCert != e.BlockExpiredCert {
+					t.Errorf("line %d: expected %+v, got %+v", i, e, r)
+				}
+			}
+		})
+	}
+	if _, err := Render(certs, ReportOptions{Layout: "wrong"}); !errors.Is(err, ErrUnknownLayout) {
+		t.Errorf("expected ErrUnknownLayout, got %v", err)
+	}
+}
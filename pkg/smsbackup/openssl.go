@@ -0,0 +1,166 @@
+package smsbackup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// openssl enc container: "Salted__", 8 bytes salt, AES-256-CBC encrypted data
+const (
+	opensslSaltLength = 8
+	opensslKeyLength  = 32
+	opensslIterations = 10000
+)
+
+// opensslKDF derives key and IV from passphrase and salt.
+type opensslKDF func(passphrase, salt []byte) (key, iv []byte)
+
+// opensslKDFs are key derivation functions used by different openssl versions:
+// -pbkdf2 option, default of openssl 1.1+ and default of older versions.
+var opensslKDFs = []opensslKDF{
+	func(passphrase, salt []byte) ([]byte, []byte) {
+		k := pbkdf2.Key(passphrase, salt, opensslIterations, opensslKeyLength+aes.BlockSize, sha256.New)
+		return k[:opensslKeyLength], k[opensslKeyLength:]
+	},
+	func(passphrase, salt []byte) ([]byte, []byte) {
+		return bytesToKey(sha256.New, passphrase, salt)
+	},
+	func(passphrase, salt []byte) ([]byte, []byte) {
+		return bytesToKey(md5.New, passphrase, salt)
+	},
+}
+
+// bytesToKey is openssl EVP_BytesToKey with one iteration.
+func bytesToKey(newHash func() hash.Hash, passphrase, salt []byte) ([]byte, []byte) {
+	var result, prev []byte
+	for len(result) < opensslKeyLength+aes.BlockSize {
+		h := newHash()
+		h.Write(prev)
+		h.Write(passphrase)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		result = append(result, prev...)
+	}
+	return result[:opensslKeyLength], result[opensslKeyLength : opensslKeyLength+aes.BlockSize]
+}
+
+// isArchive checks whether decrypted data starts with ZIP local file header
+// or gzip header with deflate compression and no reserved flags.
+func isArchive(head []byte) bool {
+	if bytes.HasPrefix(head, zipMagic) {
+		return true
+	}
+	return len(head) >= 4 && bytes.HasPrefix(head, gzipMagic) && head[2] == 8 && head[3]&0xe0 == 0
+}
+
+// cbcReaderAt decrypts AES-CBC data at any offset, so encrypted backup is
+// read in place and its plaintext is never written to disk.
+type cbcReaderAt struct {
+	// r is encrypted data
+	r     io.ReaderAt
+	block cipher.Block
+	iv    []byte
+	// size of the decrypted data without padding
+	size int64
+}
+
+var _ io.ReaderAt = &cbcReaderAt{}
+
+// decrypt decrypts blocks from first to last offset (both are multiples of
+// the block size).
+func (c *cbcReaderAt) decrypt(first, last int64) ([]byte, error) {
+	iv := c.iv
+	start := first
+	if first > 0 {
+		start -= aes.BlockSize
+	}
+	buf := make([]byte, last-start)
+	if _, err := c.r.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+	if first > 0 {
+		iv, buf = buf[:aes.BlockSize], buf[aes.BlockSize:]
+	}
+	cipher.NewCBCDecrypter(c.block, iv).CryptBlocks(buf, buf)
+	return buf, nil
+}
+
+func (c *cbcReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= c.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(p)), c.size)
+	first := off / aes.BlockSize * aes.BlockSize
+	last := (end + aes.BlockSize - 1) / aes.BlockSize * aes.BlockSize
+	buf, err := c.decrypt(first, last)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, buf[off-first:end-first])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// padding returns length of PKCS#7 padding of the last block or
+// ErrCorruptedPadding if it is not valid.
+func padding(last []byte) (int, error) {
+	pad := int(last[len(last)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(last[len(last)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return 0, ErrCorruptedPadding
+	}
+	return pad, nil
+}
+
+// decryptOpenSSL returns reader of the decrypted openssl enc container of
+// given size. Key derivation function is chosen by checking that decrypted
+// data starts with known archive header and has valid padding.
+func decryptOpenSSL(r io.ReaderAt, size int64, passphrase string) (*cbcReaderAt, error) {
+	if passphrase == "" {
+		return nil, ErrPassphrase
+	}
+	header := make([]byte, len(opensslMagic)+opensslSaltLength)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	salt := header[len(opensslMagic):]
+	length := size - int64(len(header))
+	if length <= 0 || length%aes.BlockSize != 0 {
+		return nil, ErrCorruptedPadding
+	}
+	encrypted := io.NewSectionReader(r, int64(len(header)), length)
+	for _, kdf := range opensslKDFs {
+		key, iv := kdf([]byte(passphrase), salt)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		c := &cbcReaderAt{r: encrypted, block: block, iv: iv, size: length}
+		head, err := c.decrypt(0, aes.BlockSize)
+		if err != nil {
+			return nil, err
+		}
+		if !isArchive(head) {
+			continue
+		}
+		last, err := c.decrypt(length-aes.BlockSize, length)
+		if err != nil {
+			return nil, err
+		}
+		pad, err := padding(last)
+		if err != nil {
+			continue
+		}
+		c.size -= int64(pad)
+		return c, nil
+	}
+	return nil, ErrWrongPassphrase
+}
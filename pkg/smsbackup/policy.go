@@ -0,0 +1,225 @@
+package smsbackup
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule types
+const (
+	// RuleMinRSABits - RSA key is shorter than Value bits
+	RuleMinRSABits = "min_rsa_bits"
+	// RuleMinECDSABits - ECDSA key is shorter than Value bits
+	RuleMinECDSABits = "min_ecdsa_bits"
+	// RuleForbiddenSignature - signature algorithm contains one of comma
+	// separated Value items, e.g. "SHA1,MD5"
+	RuleForbiddenSignature = "forbidden_signature"
+	// RuleMaxValidityDays - validity period is longer than Value days
+	RuleMaxValidityDays = "max_validity_days"
+	// RuleMinVersion - certificate version is less than Value
+	RuleMinVersion = "min_version"
+)
+
+// Severities of the findings
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+var (
+	ErrUnknownRuleType = errors.New("unknown rule type")
+	ErrBadRuleValue    = errors.New("bad rule value")
+)
+
+// Rule of the policy.
+type Rule struct {
+	Name string
+	Type string
+	// Value is a threshold of the rule, its meaning depends on Type
+	Value string
+	// Severity of the findings, SeverityMedium if empty
+	Severity string
+}
+
+// DefaultRules are used if no rules are configured.
+var DefaultRules = []Rule{
+	{Name: "weak-rsa-key", Type: RuleMinRSABits, Value: "2048", Severity: SeverityHigh},
+	{Name: "weak-signature", Type: RuleForbiddenSignature, Value: "SHA1,MD5,MD2", Severity: SeverityHigh},
+	{Name: "long-validity", Type: RuleMaxValidityDays, Value: "398", Severity: SeverityMedium},
+	{Name: "old-version", Type: RuleMinVersion, Value: "3", Severity: SeverityMedium},
+}
+
+// Finding is a violation of the policy rule by the certificate.
+type Finding struct {
+	Rule     string
+	Severity string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s (%s): %s", f.Rule, f.Severity, f.Message)
+}
+
+// check returns message if certificate violates the rule.
+type check func(cert *x509.Certificate) (string, bool)
+
+// Policy evaluates certificates against rules.
+type Policy struct {
+	rules  []Rule
+	checks []check
+}
+
+// NewPolicy returns policy for rules. It returns ErrUnknownRuleType or
+// ErrBadRuleValue if some rule is wrong.
+func NewPolicy(rules []Rule) (*Policy, error) {
+	p := &Policy{}
+	for _, rule := range rules {
+		c, err := newCheck(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if rule.Severity == "" {
+			rule.Severity = SeverityMedium
+		}
+		p.rules = append(p.rules, rule)
+		p.checks = append(p.checks, c)
+	}
+	return p, nil
+}
+
+func newCheck(rule Rule) (check, error) {
+	if rule.Type == RuleForbiddenSignature {
+		var forbidden []string
+		for _, s := range strings.Split(rule.Value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				forbidden = append(forbidden, strings.ToUpper(s))
+			}
+		}
+		if len(forbidden) == 0 {
+			return nil, fmt.Errorf("%w: %q", ErrBadRuleValue, rule.Value)
+		}
+		return func(cert *x509.Certificate) (string, bool) {
+			algorithm := cert.SignatureAlgorithm.String()
+			for _, s := range forbidden {
+				if strings.Contains(strings.ToUpper(algorithm), s) {
+					return "signature algorithm " + algorithm, true
+				}
+			}
+			return "", false
+		}, nil
+	}
+	value, err := strconv.Atoi(rule.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrBadRuleValue, rule.Value)
+	}
+	switch rule.Type {
+	case RuleMinRSABits:
+		return func(cert *x509.Certificate) (string, bool) {
+			key, ok := cert.PublicKey.(*rsa.PublicKey)
+			if !ok || key.N.BitLen() >= value {
+				return "", false
+			}
+			return fmt.Sprintf("RSA key is %d bits, minimum is %d", key.N.BitLen(), value), true
+		}, nil
+	case RuleMinECDSABits:
+		return func(cert *x509.Certificate) (string, bool) {
+			key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+			if !ok || key.Curve.Params().BitSize >= value {
+				return "", false
+			}
+			return fmt.Sprintf("ECDSA key is %d bits, minimum is %d", key.Curve.Params().BitSize, value), true
+		}, nil
+	case RuleMaxValidityDays:
+		return func(cert *x509.Certificate) (string, bool) {
+			days := int(cert.NotAfter.Sub(cert.NotBefore) / (24 * time.Hour))
+			if days <= value {
+				return "", false
+			}
+			return fmt.Sprintf("validity period is %d days, maximum is %d", days, value), true
+		}, nil
+	case RuleMinVersion:
+		return func(cert *x509.Certificate) (string, bool) {
+			if cert.Version >= value {
+				return "", false
+			}
+			return fmt.Sprintf("certificate version is %d, minimum is %d", cert.Version, value), true
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownRuleType, rule.Type)
+}
+
+// Evaluate returns findings of the certificate. Nil policy has no findings.
+func (p *Policy) Evaluate(cert *x509.Certificate) (findings []Finding) {
+	if p == nil {
+		return nil
+	}
+	for i, c := range p.checks {
+		message, violated := c(cert)
+		if !violated {
+			continue
+		}
+		findings = append(findings, Finding{
+			Rule:     p.rules[i].Name,
+			Severity: p.rules[i].Severity,
+			Message:  message,
+		})
+	}
+	return
+}
+
+// FindingLine is a line of the findings report.
+type FindingLine struct {
+	Rule        string `csv:"Rule"`
+	Severity    string `csv:"Severity"`
+	Message     string `csv:"Message"`
+	Certificate string `csv:"Certificate"`
+	Thumbprint  string `csv:"Thumbprint"`
+	Devices     string `csv:"Devices"`
+	SMS         string `csv:"SMS"`
+}
+
+// FindingsReport returns line per finding of each certificate. Devices of
+// all report lines of the certificate are joined.
+func FindingsReport(report []ReportLine) []FindingLine {
+	type key struct {
+		sms, thumbprint, name, rule string
+	}
+	var result []FindingLine
+	index := make(map[key]int)
+	for _, line := range report {
+		for _, f := range line.FindingList {
+			k := key{line.SMS, line.Thumbprint, line.CertName, f.Rule}
+			i, ok := index[k]
+			if !ok {
+				i = len(result)
+				index[k] = i
+				result = append(result, FindingLine{
+					Rule:        f.Rule,
+					Severity:    f.Severity,
+					Message:     f.Message,
+					Certificate: line.CertName,
+					Thumbprint:  line.Thumbprint,
+					SMS:         line.SMS,
+				})
+			}
+			var devices []string
+			if result[i].Devices != "" {
+				devices = strings.Split(result[i].Devices, ",")
+			}
+			for _, name := range strings.Split(line.IpsName, ",") {
+				if name != "" {
+					devices = appendUnique(devices, name)
+				}
+			}
+			result[i].Devices = strings.Join(devices, ",")
+		}
+	}
+	return result
+}
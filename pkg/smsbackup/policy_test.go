@@ -0,0 +1,74 @@
+package smsbackup
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+func rsaKey(bits uint) *rsa.PublicKey {
+	return &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), bits-1), E: 65537}
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := NewPolicy(DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := time.Now()
+	testCases := []struct {
+		name  string
+		cert  *x509.Certificate
+		rules []string
+	}{
+		{"compliant", &x509.Certificate{
+			PublicKey: rsaKey(2048), SignatureAlgorithm: x509.SHA256WithRSA, Version: 3,
+			NotBefore: n, NotAfter: n.Add(398 * 24 * time.Hour),
+		}, nil},
+		{"weak", &x509.Certificate{
+			PublicKey: rsaKey(1024), SignatureAlgorithm: x509.SHA1WithRSA, Version: 1,
+			NotBefore: n, NotAfter: n.Add(399 * 24 * time.Hour),
+		}, []string{"weak-rsa-key", "weak-signature", "long-validity", "old-version"}},
+		{"md5", &x509.Certificate{
+			PublicKey: rsaKey(4096), SignatureAlgorithm: x509.MD5WithRSA, Version: 3,
+			NotBefore: n, NotAfter: n.Add(time.Hour),
+		}, []string{"weak-signature"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rules []string
+			for _, f := range policy.Evaluate(tc.cert) {
+				rules = append(rules, f.Rule)
+			}
+			if !slices.Equal(rules, tc.rules) {
+				t.Errorf("expected %v, got %v", tc.rules, rules)
+			}
+		})
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	if _, err := NewPolicy([]Rule{{Name: "a", Type: "unknown", Value: "1"}}); !errors.Is(err, ErrUnknownRuleType) {
+		t.Errorf("expected ErrUnknownRuleType, got %v", err)
+	}
+	if _, err := NewPolicy([]Rule{{Name: "a", Type: RuleMinRSABits, Value: "many"}}); !errors.Is(err, ErrBadRuleValue) {
+		t.Errorf("expected ErrBadRuleValue, got %v", err)
+	}
+}
+
+func TestFindingsReport(t *testing.T) {
+	weak := []Finding{{Rule: "weak-rsa-key", Severity: SeverityHigh}}
+	report := []ReportLine{
+		{CertName: "a", Thumbprint: "1", IpsName: "ips1", FindingList: weak},
+		{CertName: "a", Thumbprint: "1", IpsName: "ips2", FindingList: weak},
+		{CertName: "b", Thumbprint: "2", IpsName: "ips1"},
+	}
+	findings := FindingsReport(report)
+	if len(findings) != 1 || findings[0].Certificate != "a" || findings[0].Devices != "ips1,ips2" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
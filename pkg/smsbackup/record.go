@@ -0,0 +1,243 @@
+package smsbackup
+
+import (
+	"crypto/x509"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// SchemaVersion is a version of the Record schema. It is incremented on
+// incompatible changes: removed or renamed fields and changed field types.
+// Adding fields does not change the version.
+//
+// Schema version 1:
+//
+//	schema_version           int       always 1
+//	name                     string    certificate name in SMS
+//	sms                      string    SMS address
+//	thumbprint               string    SMS thumbprint
+//	subject, issuer          string    X.500 names
+//	serial_number            string    decimal serial number
+//	version                  int       certificate version
+//	signature_algorithm      string    e.g. "SHA256-RSA"
+//	key_algorithm            string    "RSA", "ECDSA P-256" or "Ed25519"
+//	key_size                 int       key size in bits, -1 if unknown
+//	not_before, not_after    string    RFC 3339 dates
+//	days_to_expiry           int       negative for expired certificates
+//	status                   string    expiry status (see Status* constants)
+//	sha256, sha1             string    uppercase hex fingerprints of DER
+//	subject_alt_names        object    {"dns": [], "ip": [], "email": [], "uri": []}
+//	is_ca                    bool      basic constraints CA flag
+//	max_path_len             int       present only if path length is set
+//	key_usage, ext_key_usage []string  usage names
+//	authority_key_id         string    uppercase hex
+//	subject_key_id           string    uppercase hex
+//	crl_distribution_points  []string  URLs
+//	ocsp_servers             []string  URLs
+//	issuing_certificate_urls []string  URLs
+//	devices                  []object  {"name", "ip", "os"}
+//	ssl_server_proxies       []string  names
+//	ssl_client_proxies       []object  {"name", "min_key_length", "block_invalid", "block_expired"}
+//	ssl_client_decryptions   []object  {"name", "domains": [], "categories": [], "named_objects": []}
+//	ports                    []object  {"protocol", "start", "end"}, end is omitted for single port
+//	chain                    object    {"status", "path": [], "root_expiry"}, root_expiry is
+//	                                   RFC 3339 date omitted if root is not found
+//	findings                 []object  {"rule", "severity", "message"}
+//
+// Empty arrays and strings are omitted.
+const SchemaVersion = 1
+
+// Record is a typed report line for JSON output.
+type Record struct {
+	SchemaVersion          int                 `json:"schema_version"`
+	Name                   string              `json:"name"`
+	SMS                    string              `json:"sms,omitempty"`
+	Thumbprint             string              `json:"thumbprint,omitempty"`
+	Subject                string              `json:"subject"`
+	Issuer                 string              `json:"issuer"`
+	SerialNumber           string              `json:"serial_number"`
+	Version                int                 `json:"version"`
+	SignatureAlgorithm     string              `json:"signature_algorithm"`
+	KeyAlgorithm           string              `json:"key_algorithm"`
+	KeySize                int                 `json:"key_size"`
+	NotBefore              time.Time           `json:"not_before"`
+	NotAfter               time.Time           `json:"not_after"`
+	DaysToExpiry           int                 `json:"days_to_expiry"`
+	Status                 string              `json:"status"`
+	SHA256                 string              `json:"sha256"`
+	SHA1                   string              `json:"sha1"`
+	SubjectAltNames        SubjectAltNames     `json:"subject_alt_names"`
+	IsCA                   bool                `json:"is_ca"`
+	MaxPathLen             *int                `json:"max_path_len,omitempty"`
+	KeyUsage               []string            `json:"key_usage,omitempty"`
+	ExtKeyUsage            []string            `json:"ext_key_usage,omitempty"`
+	AuthorityKeyID         string              `json:"authority_key_id,omitempty"`
+	SubjectKeyID           string              `json:"subject_key_id,omitempty"`
+	CRLDistributionPoints  []string            `json:"crl_distribution_points,omitempty"`
+	OCSPServers            []string            `json:"ocsp_servers,omitempty"`
+	IssuingCertificateURLs []string            `json:"issuing_certificate_urls,omitempty"`
+	Devices                []RecordDevice      `json:"devices,omitempty"`
+	SSLServerProxies       []string            `json:"ssl_server_proxies,omitempty"`
+	SSLClientProxies       []RecordClientProxy `json:"ssl_client_proxies,omitempty"`
+	SSLClientDecryptions   []RecordDecryption  `json:"ssl_client_decryptions,omitempty"`
+	Ports                  []RecordPort        `json:"ports,omitempty"`
+	Chain                  RecordChain         `json:"chain"`
+	Findings               []RecordFinding     `json:"findings,omitempty"`
+}
+
+// SubjectAltNames of the Record.
+type SubjectAltNames struct {
+	DNS   []string `json:"dns,omitempty"`
+	IP    []string `json:"ip,omitempty"`
+	Email []string `json:"email,omitempty"`
+	URI   []string `json:"uri,omitempty"`
+}
+
+// RecordDevice is a device of the Record.
+type RecordDevice struct {
+	Name string `json:"name"`
+	IP   string `json:"ip,omitempty"`
+	OS   string `json:"os,omitempty"`
+}
+
+// RecordClientProxy is an SSL client proxy of the Record.
+type RecordClientProxy struct {
+	Name         string `json:"name"`
+	MinKeyLength int    `json:"min_key_length,omitempty"`
+	BlockInvalid bool   `json:"block_invalid"`
+	BlockExpired bool   `json:"block_expired"`
+}
+
+// RecordDecryption is an SSL client decryption rule of the Record.
+type RecordDecryption struct {
+	Name         string   `json:"name"`
+	Domains      []string `json:"domains,omitempty"`
+	Categories   []string `json:"categories,omitempty"`
+	NamedObjects []string `json:"named_objects,omitempty"`
+}
+
+// RecordPort is a port of the Record.
+type RecordPort struct {
+	Protocol string `json:"protocol"`
+	Start    uint   `json:"start"`
+	End      uint   `json:"end,omitempty"`
+}
+
+// RecordChain is an issuing chain of the Record.
+type RecordChain struct {
+	Status     string     `json:"status"`
+	Path       []string   `json:"path,omitempty"`
+	RootExpiry *time.Time `json:"root_expiry,omitempty"`
+}
+
+// RecordFinding is a policy finding of the Record.
+type RecordFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Record returns typed version of the report line.
+func (r *ReportLine) Record() Record {
+	record := Record{
+		SchemaVersion: SchemaVersion,
+		Name:          r.CertName,
+		SMS:           r.SMS,
+		Thumbprint:    r.Thumbprint,
+		Status:        r.Status,
+		Chain: RecordChain{
+			Status: r.chain.Status,
+			Path:   r.chain.Path,
+		},
+	}
+	record.DaysToExpiry, _ = strconv.Atoi(r.DaysToExpiry)
+	if !r.chain.RootExpiry.IsZero() {
+		rootExpiry := r.chain.RootExpiry
+		record.Chain.RootExpiry = &rootExpiry
+	}
+	if cert := r.cert; cert != nil {
+		record.setX509(cert)
+	}
+	for _, f := range r.FindingList {
+		record.Findings = append(record.Findings, RecordFinding(f))
+	}
+	var ports []Port
+	for _, d := range r.deployments {
+		if d.Device != nil {
+			record.Devices = appendUnique(record.Devices, RecordDevice{Name: d.Device.Name, IP: d.Device.IP, OS: d.Device.TOS})
+		}
+		if d.SSLServer != nil {
+			record.SSLServerProxies = appendUnique(record.SSLServerProxies, d.SSLServer.Name)
+		}
+		if p := d.ClientProxy; p != nil {
+			record.SSLClientProxies = appendUnique(record.SSLClientProxies, RecordClientProxy{
+				Name:         p.Name,
+				MinKeyLength: p.MinKeyLength,
+				BlockInvalid: p.BlockInvalid,
+				BlockExpired: p.BlockExpired,
+			})
+			for _, d := range p.Decryptions {
+				if !slices.ContainsFunc(record.SSLClientDecryptions, func(rd RecordDecryption) bool { return rd.Name == d.Name }) {
+					record.SSLClientDecryptions = append(record.SSLClientDecryptions, RecordDecryption{
+						Name:         d.Name,
+						Domains:      d.Domains,
+						Categories:   d.Categories,
+						NamedObjects: d.NamedObjects,
+					})
+				}
+			}
+		}
+		for _, port := range d.Ports {
+			ports = appendUnique(ports, port)
+		}
+	}
+	for _, port := range ports {
+		record.Ports = append(record.Ports, RecordPort(port))
+	}
+	return record
+}
+
+// setX509 sets certificate fields.
+func (record *Record) setX509(cert *x509.Certificate) {
+	record.Subject = cert.Subject.String()
+	record.Issuer = cert.Issuer.String()
+	record.SerialNumber = cert.SerialNumber.String()
+	record.Version = cert.Version
+	record.SignatureAlgorithm = cert.SignatureAlgorithm.String()
+	record.KeyAlgorithm = keyAlgorithm(cert)
+	record.KeySize = getKeySize(cert)
+	record.NotBefore = cert.NotBefore
+	record.NotAfter = cert.NotAfter
+	record.SHA256 = sha256Fingerprint(cert)
+	record.SHA1 = sha1Fingerprint(cert)
+	record.SubjectAltNames.DNS = cert.DNSNames
+	record.SubjectAltNames.Email = cert.EmailAddresses
+	for _, ip := range cert.IPAddresses {
+		record.SubjectAltNames.IP = append(record.SubjectAltNames.IP, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		record.SubjectAltNames.URI = append(record.SubjectAltNames.URI, uri.String())
+	}
+	record.IsCA = cert.BasicConstraintsValid && cert.IsCA
+	if record.IsCA && (cert.MaxPathLen > 0 || cert.MaxPathLenZero) {
+		maxPathLen := cert.MaxPathLen
+		record.MaxPathLen = &maxPathLen
+	}
+	record.KeyUsage = keyUsages(cert)
+	record.ExtKeyUsage = extKeyUsages(cert)
+	record.AuthorityKeyID = fingerprint(cert.AuthorityKeyId)
+	record.SubjectKeyID = fingerprint(cert.SubjectKeyId)
+	record.CRLDistributionPoints = cert.CRLDistributionPoints
+	record.OCSPServers = cert.OCSPServer
+	record.IssuingCertificateURLs = cert.IssuingCertificateURL
+}
+
+// Records returns typed versions of the report lines.
+func Records(report []ReportLine) []Record {
+	records := make([]Record, len(report))
+	for i := range report {
+		records[i] = report[i].Record()
+	}
+	return records
+}
@@ -0,0 +1,52 @@
+package smsbackup
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	ips := &Device{ShortID: 1, Name: "ips1", IP: "10.0.0.1", TOS: "6.0"}
+	web := Deployment{SSLServer: &SSLServer{ID: "a", Name: "web"}, Ports: []Port{{Protocol: "TCP", Start: 443}}}
+	mail := Deployment{SSLServer: &SSLServer{ID: "b", Name: "mail"}, Ports: []Port{{Protocol: "TCP", Start: 993, End: 995}}}
+	certs := []*Certificate{{
+		Name:        "shared",
+		PEM:         selfSigned(t, "shared"),
+		Deployments: deployments([]*Device{ips}, append(deviceUses([]*Device{ips}, web), deviceUses([]*Device{ips}, mail)...)),
+		Chain:       Chain{Status: ChainSelfSigned, Path: []string{"shared"}},
+	}}
+	report, err := Render(certs, ReportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := Records(report)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r.SchemaVersion != SchemaVersion || r.KeySize != 256 || r.KeyAlgorithm != "ECDSA P-256" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if !slices.Equal(r.SSLServerProxies, []string{"web", "mail"}) || len(r.Devices) != 1 || len(r.Ports) != 2 || r.Ports[1].End != 995 {
+		t.Errorf("unexpected deployments %+v %+v %+v", r.SSLServerProxies, r.Devices, r.Ports)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, decoded["not_after"].(string)); err != nil {
+		t.Errorf("not_after is not RFC 3339: %v", err)
+	}
+	if _, ok := decoded["key_size"].(float64); !ok {
+		t.Errorf("key_size is not a number: %v", decoded["key_size"])
+	}
+	if chain := decoded["chain"].(map[string]any); chain["status"] != ChainSelfSigned {
+		t.Errorf("unexpected chain %v", chain)
+	}
+}
@@ -1,17 +1,18 @@
 package smsbackup
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"database/sql"
-	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
-
-	"github.com/mpkondrashin/certlist/pkg/config"
-	"github.com/spf13/viper"
+	"strings"
+	"time"
 )
 
 /*
@@ -46,8 +47,41 @@ type ReportLine struct {
 	SubjectName        string `csv:"[SubjectName]"`
 	Version            string `csv:"[Version]"`
 	// Extra
-	SSLServerProxies string
-	CertName         string
+	SSLServerProxies     string
+	SSLClientProxies     string
+	MinKeyLength         string
+	BlockInvalidCert     string
+	BlockExpiredCert     string
+	SSLClientDecryptions string
+	CertName             string
+	SMS                  string
+	// Expiry
+	DaysToExpiry string
+	Status       string
+	// Chain
+	ChainStatus string
+	ChainPath   string
+	RootExpiry  string
+	// Policy
+	Findings    string
+	FindingList []Finding `csv:"-"`
+	// Typed data for Record
+	cert        *x509.Certificate
+	chain       Chain
+	deployments []Deployment
+	// X.509 details
+	KeyAlgorithm           string
+	SubjectAltNames        string
+	SHA256Fingerprint      string
+	SHA1Fingerprint        string
+	BasicConstraints       string
+	KeyUsage               string
+	ExtKeyUsage            string
+	AuthorityKeyID         string
+	SubjectKeyID           string
+	CRLDistributionPoints  string
+	OCSPServers            string
+	IssuingCertificateURLs string
 }
 
 /*
@@ -60,31 +94,76 @@ type ReportLine struct {
 	}
 */
 
-func (r *ReportLine) GetX509(certData []byte) error {
-	block, _ := pem.Decode(certData)
-	if block == nil {
-		return ErrFailedToParsePEMCertificate
-	}
-	cert, err := x509.ParseCertificate(block.Bytes)
+// ReportOptions control report generation.
+type ReportOptions struct {
+	// NoTZ excludes timezone from dates
+	NoTZ bool
+	// Layout of the report: LayoutDevice (default), LayoutFlat or LayoutGrouped
+	Layout string
+	// CriticalDays and WarningDays are thresholds of days to expiry for
+	// StatusCritical and StatusWarning
+	CriticalDays int
+	WarningDays  int
+	// Policy to evaluate certificates against. No findings if nil
+	Policy *Policy
+}
+
+func (r *ReportLine) GetX509(certData []byte, options ReportOptions) error {
+	cert, err := parsePEM(certData)
 	if err != nil {
 		return err
 	}
+	r.cert = cert
 	r.IssuerName = cert.Issuer.String()
-	if viper.GetBool(config.OutputNoTZ) {
-		r.ExpirationDate = cert.NotAfter.Format("2006-01-02 15:04:05.000")
-		r.EffectiveDate = cert.NotBefore.Format("2006-01-02 15:04:05.000")
-	} else {
-		r.ExpirationDate = cert.NotAfter.String()
-		r.EffectiveDate = cert.NotBefore.String()
-	}
+	r.ExpirationDate = formatDate(cert.NotAfter, options)
+	r.EffectiveDate = formatDate(cert.NotBefore, options)
 	r.KeySize0 = strconv.Itoa(getKeySize(cert))
 	r.SerialNumber = cert.SerialNumber.String()
 	r.SignatureAlgorithm = cert.SignatureAlgorithm.String()
 	r.SubjectName = cert.Subject.String()
 	r.Version = strconv.Itoa(cert.Version)
+	days, status := expiryStatus(cert, options)
+	r.DaysToExpiry = strconv.Itoa(days)
+	r.Status = status
+	r.KeyAlgorithm = keyAlgorithm(cert)
+	r.SubjectAltNames = subjectAltNames(cert)
+	r.SHA256Fingerprint = sha256Fingerprint(cert)
+	r.SHA1Fingerprint = sha1Fingerprint(cert)
+	r.BasicConstraints = basicConstraints(cert)
+	r.KeyUsage = keyUsage(cert)
+	r.ExtKeyUsage = extKeyUsage(cert)
+	r.AuthorityKeyID = fingerprint(cert.AuthorityKeyId)
+	r.SubjectKeyID = fingerprint(cert.SubjectKeyId)
+	r.CRLDistributionPoints = strings.Join(cert.CRLDistributionPoints, ",")
+	r.OCSPServers = strings.Join(cert.OCSPServer, ",")
+	r.IssuingCertificateURLs = strings.Join(cert.IssuingCertificateURL, ",")
+	r.FindingList = options.Policy.Evaluate(cert)
+	var findings []string
+	for _, f := range r.FindingList {
+		findings = append(findings, f.String())
+	}
+	r.Findings = strings.Join(findings, "; ")
 	return nil
 }
 
+// formatDate formats date with or without timezone.
+func formatDate(t time.Time, options ReportOptions) string {
+	if options.NoTZ {
+		return t.Format("2006-01-02 15:04:05.000")
+	}
+	return t.String()
+}
+
+// setChain sets chain fields.
+func (r *ReportLine) setChain(chain Chain, options ReportOptions) {
+	r.chain = chain
+	r.ChainStatus = chain.Status
+	r.ChainPath = strings.Join(chain.Path, " > ")
+	if !chain.RootExpiry.IsZero() {
+		r.RootExpiry = formatDate(chain.RootExpiry, options)
+	}
+}
+
 var ErrFailedToParsePEMCertificate = fmt.Errorf("failed to parse certificate PEM")
 
 // getKeySize returns the key size in bits from an x509 certificate
@@ -94,95 +173,160 @@ func getKeySize(cert *x509.Certificate) int {
 		return pub.N.BitLen()
 	case *ecdsa.PublicKey:
 		return pub.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 8 * len(pub)
 	default:
 		return -1 // Unknown or unsupported key type
 	}
 }
 
-var query = `
-SELECT
-	nc.NAME,
-	nc.THUMBPRINT,
-	nc.CERT_BYTES,
-	GROUP_CONCAT(ss.NAME SEPARATOR ',') AS SSL_SERVER_PROXIES,
-	COALESCE(td.DISPLAY_NAME,''),
-	COALESCE(sslp.START_PORT,''),
-	COALESCE(td.IP_ADDRESS,''),
-	COALESCE(td.SOFTWARE_VERSION,'')
-FROM NAMED_CERTIFICATE nc
-LEFT JOIN DEVICE_CERTIFICATE dc ON nc.ID = dc.NAMED_CERTIFICATE_ID
-LEFT JOIN TPT_DEVICE td ON dc.DEVICE_SHORT_ID = td.SHORT_ID
-LEFT JOIN SSL_SERVER_CERTIFICATES ssc ON nc.ID = ssc.NAMED_CERTIFICATE_ID
-LEFT JOIN SSL_SERVER ss ON ssc.SSL_SERVER_ID = ss.SSL_SERVER_ID
-LEFT JOIN SSL_SERVER_PORT sslp ON ss.SSL_SERVER_ID = sslp.SSL_SERVER_ID
-WHERE nc.PRIVATE_KEY_EXPECTED=1
-GROUP BY ss.NAME;`
-
-//GROUP BY nc.ID,nc.NAME,nc.THUMBPRINT,nc.CERT_BYTES;`
+// ReportTables are tables used by GenerateReport_. Only these tables
+// need to be loaded from the dump.
+var ReportTables = []string{
+	"NAMED_CERTIFICATE",
+	"DEVICE_CERTIFICATE",
+	"TPT_DEVICE",
+	"SSL_SERVER",
+	"SSL_SERVER_CERTIFICATES",
+	"SSL_SERVER_PORT",
+	"SSL_CLIENT_PROXY",
+	"SSL_CLIENT_PROXY_PORT",
+	"POLICY",
+	"PROFILE_INSTALL_INVENTORY",
+	"VIRTUAL_SEGMENT",
+	"SSL_CLIENT_DECRYPT",
+	"SSL_CLIENT_DECRYPT_DOMAIN",
+	"SSL_CLIENT_DECRYPT_CAT",
+	"SSL_CLIENT_DECRYPT_NAMED_OBJ",
+	"SSL_CATEGORY",
+}
 
-// NAMED_CERTIFICATE.ID,
-func GenerateReport_(db *sql.DB) (report []ReportLine, err error) {
-	/*	query := `
-		SELECT
-		    nc.NAME as cert_name,
-		    nc.THUMBPRINT,
-		    nc.CERT_BYTES,
-		    GROUP_CONCAT(DISTINCT ss.PROXY_NAME) as ssl_server_proxies,
-		    GROUP_CONCAT(DISTINCT td.IPS_NAME) as ips_name,
-		    GROUP_CONCAT(DISTINCT td.MANAGMENT_IP) as managment_ip,
-		    GROUP_CONCAT(DISTINCT td.TOS) as tos
-		FROM NAMED_CERTIFICATE nc
-		LEFT JOIN DEVICE_CERTIFICATE dc ON nc.ID = dc.NAMED_CERTIFICATE_ID
-		LEFT JOIN TPT_DEVICE td ON dc.DEVICE_SHORT_ID = td.SHORT_ID
-		LEFT JOIN SSL_SERVER_CERTIFICATES ssc ON nc.ID = ssc.NAMED_CERTIFICATE_ID
-		LEFT JOIN SSL_SERVER ss ON ssc.SSL_SERVER_ID = ss.SSL_SERVER_ID
-		WHERE nc.PRIVATE_KEY_EXPECTED = 1
-		GROUP BY nc.NAME, nc.THUMBPRINT, nc.CERT_BYTES`*/
-	rows, err := db.Query(query)
+// GenerateReport_ loads certificates from db and renders them. It uses
+// pkg/model iterators, so it works both on MariaDB and on in-memory dump
+// (see pkg/dumpreader).
+func GenerateReport_(ctx context.Context, db *sql.DB, options ReportOptions) ([]ReportLine, error) {
+	certificates, err := LoadCertificates(ctx, db)
 	if err != nil {
 		return nil, err
 	}
+	return Render(certificates, options)
+}
 
-	defer rows.Close()
-	for rows.Next() {
-		var reportLine ReportLine
-		//&reportLine.Id,
-		var cn, tp, ccb, ssp, in, sp, mip, tos sql.NullString
-		err := rows.Scan(&cn, &tp, &ccb, &ssp, &in, &sp, &mip, &tos)
-		if err != nil {
-			return nil, err
+// Report layouts
+const (
+	// LayoutDevice - line per certificate and device, SSL servers and client proxies are joined
+	LayoutDevice = "device"
+	// LayoutFlat - line per certificate, device and SSL server or client proxy
+	LayoutFlat = "flat"
+	// LayoutGrouped - line per certificate, devices, SSL servers and client proxies are joined
+	LayoutGrouped = "grouped"
+)
+
+var ErrUnknownLayout = errors.New("unknown report layout")
+
+// layoutKey returns function grouping deployments into report lines.
+func layoutKey(layout string) (func(i int, d Deployment) any, error) {
+	switch layout {
+	case "", LayoutDevice:
+		return func(_ int, d Deployment) any { return d.Device }, nil
+	case LayoutFlat:
+		return func(i int, _ Deployment) any { return i }, nil
+	case LayoutGrouped:
+		return func(int, Deployment) any { return nil }, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownLayout, layout)
+}
+
+// CheckLayout returns ErrUnknownLayout if layout is not supported.
+func CheckLayout(layout string) error {
+	_, err := layoutKey(layout)
+	return err
+}
+
+// Render returns report lines for certificates using options.Layout.
+func Render(certificates []*Certificate, options ReportOptions) ([]ReportLine, error) {
+	key, err := layoutKey(options.Layout)
+	if err != nil {
+		return nil, err
+	}
+	var report []ReportLine
+	for _, cert := range certificates {
+		reportLine := ReportLine{
+			CertName:   cert.Name,
+			Thumbprint: cert.Thumbprint,
 		}
-		if cn.Valid {
-			reportLine.CertName = cn.String
+		if err := reportLine.GetX509(cert.PEM, options); err != nil {
+			return nil, fmt.Errorf("%s: %w", cert.Name, err)
 		}
-		if tp.Valid {
-			reportLine.Thumbprint = tp.String
+		reportLine.setChain(cert.Chain, options)
+		log.Printf("Certificate name: %s", reportLine.CertName)
+		if len(cert.Deployments) == 0 {
+			report = append(report, reportLine)
+			continue
 		}
-		if ssp.Valid {
-			reportLine.SSLServerProxies = ssp.String
+		var keys []any
+		groups := make(map[any][]Deployment)
+		for i, d := range cert.Deployments {
+			k := key(i, d)
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], d)
 		}
-		if in.Valid {
-			reportLine.IpsName = in.String
+		for _, k := range keys {
+			line := reportLine
+			line.setDeployments(groups[k])
+			report = append(report, line)
 		}
-		if sp.Valid {
-			reportLine.StartPort = sp.String
+	}
+	return report, nil
+}
+
+// Deployments returns deployments of the certificate listed in the line.
+func (r *ReportLine) Deployments() []Deployment {
+	return r.deployments
+}
+
+// setDeployments sets device, SSL server and SSL client proxy fields joining
+// unique values.
+func (r *ReportLine) setDeployments(deployments []Deployment) {
+	var names, ips, tos, proxies, ports []string
+	var clientProxies, minKeyLengths, blockInvalid, blockExpired, decryptions []string
+	r.deployments = deployments
+	for _, d := range deployments {
+		if d.Device != nil {
+			names = appendUnique(names, d.Device.Name)
+			ips = appendUnique(ips, d.Device.IP)
+			tos = appendUnique(tos, d.Device.TOS)
 		}
-		if mip.Valid {
-			reportLine.ManagmentIP = mip.String
+		if d.SSLServer != nil {
+			proxies = appendUnique(proxies, d.SSLServer.Name)
 		}
-		if tos.Valid {
-			reportLine.Tos = tos.String
+		if p := d.ClientProxy; p != nil {
+			clientProxies = appendUnique(clientProxies, p.Name)
+			if p.MinKeyLength != 0 {
+				minKeyLengths = appendUnique(minKeyLengths, strconv.Itoa(p.MinKeyLength))
+			}
+			blockInvalid = appendUnique(blockInvalid, strconv.FormatBool(p.BlockInvalid))
+			blockExpired = appendUnique(blockExpired, strconv.FormatBool(p.BlockExpired))
+			for _, d := range p.Decryptions {
+				decryptions = appendUnique(decryptions, d.Name)
+			}
 		}
-		if err := reportLine.GetX509([]byte(ccb.String)); err != nil {
-			return nil, err
+		for _, port := range d.Ports {
+			ports = appendUnique(ports, strconv.Itoa(int(port.Start)))
 		}
-		log.Printf("Certificate name: %s", reportLine.CertName)
-		report = append(report, reportLine)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return report, nil
+	r.IpsName = strings.Join(names, ",")
+	r.ManagmentIP = strings.Join(ips, ",")
+	r.Tos = strings.Join(tos, ",")
+	r.SSLServerProxies = strings.Join(proxies, ",")
+	r.StartPort = strings.Join(ports, ",")
+	r.SSLClientProxies = strings.Join(clientProxies, ",")
+	r.MinKeyLength = strings.Join(minKeyLengths, ",")
+	r.BlockInvalidCert = strings.Join(blockInvalid, ",")
+	r.BlockExpiredCert = strings.Join(blockExpired, ",")
+	r.SSLClientDecryptions = strings.Join(decryptions, ",")
 }
 
 /*
@@ -1,90 +1,233 @@
 package smsbackup
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
+	"sync/atomic"
 )
 
-var ErrNotFound = errors.New("file not found in tag.gz archive")
+var (
+	ErrNotFound      = errors.New("file not found in backup")
+	ErrUnknownFormat = errors.New("unknown backup format")
+)
+
+const DumpFileName = "noalerts.mysqldump"
+
+// Magic bytes of supported backup formats
+var (
+	zipMagic     = []byte("PK\x03\x04")
+	gzipMagic    = []byte{0x1f, 0x8b}
+	opensslMagic = []byte("Salted__")
+)
 
-func ExtractDump(backupPath string) (string, error) {
-	folder := filepath.Dir(backupPath)
-	fileName := "noalerts.mysqldump"
-	filePath := filepath.Join(folder, fileName)
-	return filePath, extractFileFromZIP(backupPath, fileName, filePath)
+// maxListed is maximum number of archive entries listed in ErrNotFound error.
+const maxListed = 20
+
+// DumpReader reads database dump directly from the backup archive
+// and counts read bytes.
+type DumpReader struct {
+	r       io.Reader
+	closers []func() error
+	size    int64
+	count   atomic.Int64
 }
 
-/*
-	func extractFileFromTarGz(tarGzPath, targetFile, outputPath string) error {
-		file, err := os.Open(tarGzPath)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
+var _ io.ReadCloser = &DumpReader{}
 
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return err
-		}
-		defer gzReader.Close()
-
-		tarReader := tar.NewReader(gzReader)
-		for {
-			header, err := tarReader.Next()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
-			if header.Name == targetFile {
-				outFile, err := os.Create(outputPath)
-				if err != nil {
-					return err
-				}
-				defer outFile.Close()
-
-				_, err = io.Copy(outFile, tarReader)
-				if err != nil {
-					return err
-				}
-
-				fmt.Printf("Extracted %s to %s\n", targetFile, outputPath)
-				return nil
-			}
+// OpenDump opens database dump inside backup file without extracting it.
+// Backup can be ZIP, tar.gz or encrypted by passphrase ZIP or openssl
+// container with one of these formats inside.
+func OpenDump(backupPath, passphrase string) (*DumpReader, error) {
+	format, err := detectFormat(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(format, zipMagic):
+		return openZip(backupPath, passphrase)
+	case bytes.HasPrefix(format, gzipMagic):
+		return openTarGz(backupPath)
+	case bytes.Equal(format, opensslMagic):
+		return openEncrypted(backupPath, passphrase)
+	}
+	return nil, fmt.Errorf("%w: %s starts with %x", ErrUnknownFormat, backupPath, format)
+}
+
+// detectFormat returns first bytes of the file.
+func detectFormat(backupPath string) ([]byte, error) {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, len(opensslMagic))
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	for _, magic := range [][]byte{zipMagic, opensslMagic} {
+		if bytes.HasPrefix(head, magic) {
+			return magic, nil
 		}
-		return fmt.Errorf("%s: %w", targetFile, ErrNotFound)
 	}
-*/
-func extractFileFromZIP(zipPath, targetFile, outputPath string) error {
-	zipReader, err := zip.OpenReader(zipPath)
+	if bytes.HasPrefix(head, gzipMagic) {
+		return gzipMagic, nil
+	}
+	return head, nil
+}
+
+// isDump checks whether archive entry is database dump.
+func isDump(name string) bool {
+	return path.Base(strings.ReplaceAll(name, "\\", "/")) == DumpFileName
+}
+
+// notFound returns ErrNotFound listing archive contents.
+func notFound(names []string) error {
+	if len(names) > maxListed {
+		names = append(names[:maxListed:maxListed], fmt.Sprintf("(%d more)", len(names)-maxListed))
+	}
+	return fmt.Errorf("%s: %w, archive contains: %s", DumpFileName, ErrNotFound, strings.Join(names, ", "))
+}
+
+func openZip(backupPath, passphrase string) (*DumpReader, error) {
+	zipReader, err := zip.OpenReader(backupPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer zipReader.Close()
+	return zipDump(&zipReader.Reader, passphrase, zipReader.Close)
+}
+
+// zipDump opens dump inside ZIP archive. closer is called when dump is closed
+// or on error.
+func zipDump(zipReader *zip.Reader, passphrase string, closer func() error) (*DumpReader, error) {
+	var names []string
 	for _, file := range zipReader.File {
-		if file.Name != targetFile {
+		names = append(names, file.Name)
+		if !isDump(file.Name) {
 			continue
 		}
-		rc, err := file.Open()
+		rc, err := openZipFile(file, passphrase)
 		if err != nil {
-			return err
+			closer()
+			return nil, err
+		}
+		return &DumpReader{
+			r:       rc,
+			closers: []func() error{rc.Close, closer},
+			size:    int64(file.UncompressedSize64),
+		}, nil
+	}
+	closer()
+	return nil, notFound(names)
+}
+
+func openTarGz(backupPath string) (*DumpReader, error) {
+	file, err := os.Open(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	return tarGzDump(file, file.Close)
+}
+
+// tarGzDump opens dump inside tar.gz archive. closer is called when dump is
+// closed or on error.
+func tarGzDump(r io.Reader, closer func() error) (*DumpReader, error) {
+	gzReader, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		closer()
+		return nil, err
+	}
+	tarReader := tar.NewReader(gzReader)
+	var names []string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
 		}
-		defer rc.Close()
-		outFile, err := os.Create(outputPath)
 		if err != nil {
-			return err
+			gzReader.Close()
+			closer()
+			return nil, err
 		}
-		defer outFile.Close()
-		_, err = io.Copy(outFile, rc)
+		names = append(names, header.Name)
+		if header.Typeflag != tar.TypeReg || !isDump(header.Name) {
+			continue
+		}
+		return &DumpReader{
+			r:       tarReader,
+			closers: []func() error{gzReader.Close, closer},
+			size:    header.Size,
+		}, nil
+	}
+	gzReader.Close()
+	closer()
+	return nil, notFound(names)
+}
+
+// openEncrypted opens dump inside encrypted backup decrypting it on the fly.
+func openEncrypted(backupPath, passphrase string) (*DumpReader, error) {
+	file, err := os.Open(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	decrypted, err := decryptOpenSSL(file, info.Size(), passphrase)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	head := make([]byte, len(zipMagic))
+	if _, err := decrypted.ReadAt(head, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if bytes.Equal(head, zipMagic) {
+		zipReader, err := zip.NewReader(decrypted, decrypted.size)
 		if err != nil {
-			return err
+			file.Close()
+			return nil, err
+		}
+		return zipDump(zipReader, passphrase, file.Close)
+	}
+	return tarGzDump(bufio.NewReaderSize(io.NewSectionReader(decrypted, 0, decrypted.size), 1<<20), file.Close)
+}
+
+func (d *DumpReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.count.Add(int64(n))
+	return n, err
+}
+
+// Count returns number of bytes read so far. Safe to call concurrently with Read.
+func (d *DumpReader) Count() int64 {
+	return d.count.Load()
+}
+
+// Size returns uncompressed size of the dump.
+func (d *DumpReader) Size() int64 {
+	return d.size
+}
+
+func (d *DumpReader) Close() error {
+	var err error
+	for _, c := range d.closers {
+		if closeErr := c(); err == nil {
+			err = closeErr
 		}
-		return nil
 	}
-	return fmt.Errorf("%s: %w", targetFile, ErrNotFound)
+	return err
 }
@@ -0,0 +1,205 @@
+package smsbackup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDump = "INSERT INTO `TPT_DEVICE` VALUES (1,'a');\n"
+
+func zipBackup(t *testing.T, name string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(testDump)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBackup(t *testing.T, name string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(testDump)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(testDump)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// opensslEncrypt does the same as openssl enc -aes-256-cbc -pbkdf2
+func opensslEncrypt(t *testing.T, data []byte, passphrase string) []byte {
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	return opensslEncryptRaw(t, append(bytes.Clone(data), bytes.Repeat([]byte{byte(pad)}, pad)...), passphrase)
+}
+
+// opensslEncryptRaw encrypts block aligned data without adding padding.
+func opensslEncryptRaw(t *testing.T, data []byte, passphrase string) []byte {
+	salt := make([]byte, opensslSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatal(err)
+	}
+	key, iv := opensslKDFs[0]([]byte(passphrase), salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Clone(data)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return append(append([]byte("Salted__"), salt...), data...)
+}
+
+func readDump(t *testing.T, data []byte, passphrase string) (string, error) {
+	path := filepath.Join(t.TempDir(), "backup")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := OpenDump(path, passphrase)
+	if err != nil {
+		return "", err
+	}
+	defer dump.Close()
+	content, err := io.ReadAll(dump)
+	if err != nil {
+		return "", err
+	}
+	if dump.Count() != dump.Size() {
+		t.Errorf("read %d bytes of %d", dump.Count(), dump.Size())
+	}
+	return string(content), nil
+}
+
+func TestOpenDump(t *testing.T) {
+	testCases := []struct {
+		name       string
+		data       []byte
+		passphrase string
+	}{
+		{"zip", zipBackup(t, DumpFileName), ""},
+		{"tar.gz", tarGzBackup(t, "backup/"+DumpFileName), ""},
+		{"encrypted zip", opensslEncrypt(t, zipBackup(t, DumpFileName), "secret"), "secret"},
+		{"encrypted tar.gz", opensslEncrypt(t, tarGzBackup(t, DumpFileName), "secret"), "secret"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := readDump(t, tc.data, tc.passphrase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content != testDump {
+				t.Errorf("expected %q, got %q", testDump, content)
+			}
+		})
+	}
+}
+
+func TestOpenDumpErrors(t *testing.T) {
+	_, err := readDump(t, zipBackup(t, "other.sql"), "")
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "other.sql") {
+		t.Errorf("expected ErrNotFound listing contents, got %v", err)
+	}
+	_, err = readDump(t, []byte(testDump), "")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	encrypted := opensslEncrypt(t, zipBackup(t, DumpFileName), "secret")
+	if _, err = readDump(t, encrypted, ""); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("expected ErrPassphrase, got %v", err)
+	}
+	if _, err = readDump(t, encrypted, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestOpenEncryptedErrors(t *testing.T) {
+	backup := zipBackup(t, DumpFileName)
+	// Valid archive header, but zero padding
+	unpadded := append(bytes.Clone(backup), make([]byte, 2*aes.BlockSize-len(backup)%aes.BlockSize)...)
+	if _, err := readDump(t, opensslEncryptRaw(t, unpadded, "secret"), "secret"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase for bad padding, got %v", err)
+	}
+	truncated := opensslEncrypt(t, backup, "secret")
+	truncated = truncated[:len(truncated)-1]
+	if _, err := readDump(t, truncated, "secret"); !errors.Is(err, ErrCorruptedPadding) {
+		t.Errorf("expected ErrCorruptedPadding for truncated backup, got %v", err)
+	}
+}
+
+func TestOpenEncryptedInPlace(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	t.Setenv("TEMP", tempDir)
+	t.Setenv("TMP", tempDir)
+	for _, backup := range [][]byte{zipBackup(t, DumpFileName), tarGzBackup(t, DumpFileName)} {
+		path := filepath.Join(t.TempDir(), "backup")
+		if err := os.WriteFile(path, opensslEncrypt(t, backup, "secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		dump, err := OpenDump(path, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("decrypted data is written to %s", entries[0].Name())
+		}
+		dump.Close()
+	}
+}
+
+func TestCBCReaderAt(t *testing.T) {
+	plain := make([]byte, 10*aes.BlockSize+5)
+	if _, err := rand.Read(plain); err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, opensslKeyLength)
+	iv := make([]byte, aes.BlockSize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := append(bytes.Clone(plain), make([]byte, aes.BlockSize-len(plain)%aes.BlockSize)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+	c := &cbcReaderAt{r: bytes.NewReader(encrypted), block: block, iv: iv, size: int64(len(plain))}
+	for _, r := range [][2]int{{0, 1}, {0, 16}, {3, 40}, {16, 16}, {17, 100}, {150, 100}} {
+		off, n := r[0], r[1]
+		buf := make([]byte, n)
+		got, err := c.ReadAt(buf, int64(off))
+		want := min(n, len(plain)-off)
+		if got != want || !bytes.Equal(buf[:got], plain[off:off+want]) {
+			t.Errorf("ReadAt(%d, %d): got %d bytes, err %v", n, off, got, err)
+		}
+		if want < n && err != io.EOF {
+			t.Errorf("ReadAt(%d, %d): expected EOF, got %v", n, off, err)
+		}
+	}
+}
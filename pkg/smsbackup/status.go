@@ -0,0 +1,72 @@
+package smsbackup
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Expiry statuses of the certificate
+const (
+	StatusExpired     = "expired"
+	StatusCritical    = "critical"
+	StatusWarning     = "warning"
+	StatusOK          = "ok"
+	StatusNotYetValid = "not-yet-valid"
+)
+
+// Statuses lists all expiry statuses.
+var Statuses = []string{StatusExpired, StatusCritical, StatusWarning, StatusOK, StatusNotYetValid}
+
+var ErrUnknownStatus = errors.New("unknown status")
+
+// now is used to calculate expiry status. Tests replace it.
+var now = time.Now
+
+// CheckStatuses returns ErrUnknownStatus if any of statuses is not supported.
+func CheckStatuses(statuses []string) error {
+	for _, status := range statuses {
+		if !slices.Contains(Statuses, status) {
+			return fmt.Errorf("%w: %s", ErrUnknownStatus, status)
+		}
+	}
+	return nil
+}
+
+// expiryStatus returns full days left till certificate expiration (negative
+// for expired certificates) and its status. Certificate is critical or
+// warning if it expires in options.CriticalDays or options.WarningDays days
+// or less.
+func expiryStatus(cert *x509.Certificate, options ReportOptions) (int, string) {
+	t := now()
+	days := int(math.Floor(cert.NotAfter.Sub(t).Hours() / 24))
+	switch {
+	case t.After(cert.NotAfter):
+		return days, StatusExpired
+	case t.Before(cert.NotBefore):
+		return days, StatusNotYetValid
+	case days <= options.CriticalDays:
+		return days, StatusCritical
+	case days <= options.WarningDays:
+		return days, StatusWarning
+	}
+	return days, StatusOK
+}
+
+// FilterStatus returns report lines having one of statuses. All lines are
+// returned if statuses is empty.
+func FilterStatus(report []ReportLine, statuses []string) []ReportLine {
+	if len(statuses) == 0 {
+		return report
+	}
+	var result []ReportLine
+	for _, line := range report {
+		if slices.Contains(statuses, line.Status) {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package smsbackup
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiryStatus(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return t0 }
+	defer func() { now = time.Now }()
+	day := 24 * time.Hour
+	options := ReportOptions{CriticalDays: 14, WarningDays: 60}
+	testCases := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		days      int
+		status    string
+	}{
+		{"expired", t0.Add(-100 * day), t0.Add(-time.Hour), -1, StatusExpired},
+		{"critical", t0.Add(-100 * day), t0.Add(14*day + time.Hour), 14, StatusCritical},
+		{"warning", t0.Add(-100 * day), t0.Add(15*day + time.Hour), 15, StatusWarning},
+		{"ok", t0.Add(-100 * day), t0.Add(61*day + time.Hour), 61, StatusOK},
+		{"not yet valid", t0.Add(time.Hour), t0.Add(100*day + time.Hour), 100, StatusNotYetValid},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cert := &x509.Certificate{NotBefore: tc.notBefore, NotAfter: tc.notAfter}
+			days, status := expiryStatus(cert, options)
+			if days != tc.days || status != tc.status {
+				t.Errorf("expected %d %s, got %d %s", tc.days, tc.status, days, status)
+			}
+		})
+	}
+}
+
+func TestFilterStatus(t *testing.T) {
+	report := []ReportLine{
+		{CertName: "a", Status: StatusOK},
+		{CertName: "b", Status: StatusExpired},
+		{CertName: "c", Status: StatusWarning},
+	}
+	if len(FilterStatus(report, nil)) != len(report) {
+		t.Error("expected all lines without filter")
+	}
+	result := FilterStatus(report, []string{StatusExpired, StatusWarning})
+	if len(result) != 2 || result[0].CertName != "b" || result[1].CertName != "c" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := CheckStatuses([]string{StatusOK, "fine"}); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("expected ErrUnknownStatus, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+-- Dump of the tables used by LoadCertificates (see inventory_test.go)
+DROP TABLE IF EXISTS `TPT_DEVICE`;
+CREATE TABLE `TPT_DEVICE` (
+  `ID` varchar(255) NOT NULL,
+  `DISPLAY_NAME` varchar(255) DEFAULT NULL,
+  `BIT_ARRAY` varchar(255) DEFAULT NULL,
+  `DEVICE_MODEL` varchar(255) DEFAULT NULL,
+  `SOFTWARE_VERSION` varchar(255) DEFAULT NULL,
+  `SERIAL_NUMBER` varchar(255) DEFAULT NULL,
+  `DESTINATION_NETWORK` varchar(255) DEFAULT NULL,
+  `GATEWAY_MASK` varchar(255) DEFAULT NULL,
+  `TIME_STAMP` varchar(255) DEFAULT NULL,
+  `TIME_ZONE` varchar(255) DEFAULT NULL,
+  `DEFAULT_POSTURE` varchar(255) DEFAULT NULL,
+  `DEVICE_MODE` varchar(255) DEFAULT NULL,
+  `EMAIL_ADMIN` varchar(255) DEFAULT NULL,
+  `NETWORK_MASK` varchar(255) DEFAULT NULL,
+  `SMTP_DOMAIN` varchar(255) DEFAULT NULL,
+  `ATTACK_SYNC_PARAMS` varchar(255) DEFAULT NULL,
+  `GLOBAL_FALLBACK_STATE` varchar(255) DEFAULT NULL,
+  `GLOBAL_FALLBACK_CAUSE` varchar(255) DEFAULT NULL,
+  `GLOBAL_FALLBACK_TIME_STAMP` varchar(255) DEFAULT NULL,
+  `DROPPED_THRESHOLD` varchar(255) DEFAULT NULL,
+  `IP_ADDRESS` varchar(255) DEFAULT NULL,
+  `SNMP_PORT` varchar(255) DEFAULT NULL,
+  `COMMUNITY` varchar(255) DEFAULT NULL,
+  `WRITE_COMMUNITY` varchar(255) DEFAULT NULL,
+  `HOST_NETMASK` varchar(255) DEFAULT NULL,
+  `SYS_DESCR` varchar(255) DEFAULT NULL,
+  `SYS_OID` varchar(255) DEFAULT NULL,
+  `LOCATION` varchar(255) DEFAULT NULL,
+  `CONTACT` varchar(255) DEFAULT NULL,
+  `CONN_TABLE_TIMEOUT` varchar(255) DEFAULT NULL,
+  `CONGESTION_DETECT_MODE` varchar(255) DEFAULT NULL,
+  `SNTP_DURATION` varchar(255) DEFAULT NULL,
+  `SNTP_OFFSET` varchar(255) DEFAULT NULL,
+  `SNTP_PORT` varchar(255) DEFAULT NULL,
+  `SNTP_RETRIES` varchar(255) DEFAULT NULL,
+  `SNTP_TIMEOUT` varchar(255) DEFAULT NULL,
+  `TRHA_CONN_STATE` varchar(255) DEFAULT NULL,
+  `NMS_COMMUNITY` varchar(255) DEFAULT NULL,
+  `NMS_EVENT_METADATA` int(11) NOT NULL,
+  `LOG_IMPORT_INTERVAL` varchar(255) DEFAULT NULL,
+  `AFC_LOG_LEVEL` varchar(255) DEFAULT NULL,
+  `TSE_LOG_THRESHOLD` varchar(255) DEFAULT NULL,
+  `TSE_LOG_PERIOD` varchar(255) DEFAULT NULL,
+  `SMTP_THRESHOLD` varchar(255) DEFAULT NULL,
+  `QUARANTINE_TIMEOUT` varchar(255) DEFAULT NULL,
+  `DNS_DOMAIN` varchar(255) DEFAULT NULL,
+  `PARENT_GROUP_ID` varchar(255) DEFAULT NULL,
+  `ZPHA_STATE` varchar(255) DEFAULT NULL,
+  `HA_FALLBACK_ACTION` varchar(255) DEFAULT NULL,
+  `HA_CONGESTION_THRESH` varchar(255) DEFAULT NULL,
+  `LB_LEARN_MODE` varchar(255) DEFAULT NULL,
+  `GATEWAY_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `SMTP_SERVER_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `SMTP_EMAIL_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `TRHA_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `PRIMARY_NTP_SERVER_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `SECONDARY_NTP_SERVER_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `DNS_PRIMARY_SERVER_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `DNS_SECONDARY_SERVER_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `IPV6_MGMT_ENABLED` varchar(255) DEFAULT NULL,
+  `IPV6_AUTO_CONFIG` varchar(255) DEFAULT NULL,
+  `IPV4_IP_ADDRESS` varchar(255) DEFAULT NULL,
+  `IPV6_IP_ADDRESS` varchar(255) DEFAULT NULL,
+  `IPV6_LINK_LOCAL_ADDRESS` varchar(255) DEFAULT NULL,
+  `IPV6_GATEWAY_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `FIPS_MODE_ENABLED` int(11) NOT NULL,
+  `FIPS_MODE_ACTIVE` int(11) NOT NULL,
+  `HA_AUTO_RECOVER_HEARTBEAT_ENABLE` int(11) NOT NULL,
+  `HA_AUTO_RECOVER_ILINK_ENABLE` int(11) NOT NULL,
+  `HA_PERF_PROTECT_ENABLE` int(11) NOT NULL,
+  `HA_PERF_PROTECT_AUTO_RESTORE_ENABLE` int(11) NOT NULL,
+  `SNMP_VERSION` int(11) NOT NULL,
+  `SNMP_CONFIG_VERSION` int(11) NOT NULL,
+  `CONN_TABLE_NON_TCP_TIMEOUT` varchar(255) DEFAULT NULL,
+  `CONN_TABLE_TRUST_TIMEOUT` varchar(255) DEFAULT NULL,
+  `HW_SERIAL` varchar(255) DEFAULT NULL,
+  `ENTITLEMENT_VERSION` varchar(255) DEFAULT NULL,
+  `REMOTE_AUTH_ENABLED` int(11) NOT NULL,
+  `REMOTE_AUTH_TIMEOUT` varchar(255) DEFAULT NULL,
+  `DEVICE_VERSION_DATA_XML` varchar(255) DEFAULT NULL,
+  `MAX_IOM_PORTS` varchar(255) DEFAULT NULL,
+  `TRHA_DEVICE_ID` varchar(255) DEFAULT NULL,
+  `TRHA_DEVICE_SERIAL` varchar(255) DEFAULT NULL,
+  `IPDB_SYNCED` int(11) NOT NULL,
+  `DEVICE_MODEL_TYPE` varchar(255) DEFAULT NULL,
+  `HA_L2FB_PREF` int(11) NOT NULL,
+  `GZIP_DECOMPRESSION` int(11) NOT NULL,
+  `IDS_CONFIGURED` int(11) NOT NULL,
+  `IDS_ACTIVE` int(11) NOT NULL,
+  `HTTP_ENCODED_RESP` int(11) NOT NULL,
+  `FLOW_STATUS` int(11) NOT NULL,
+  `API_VERSION` varchar(255) DEFAULT NULL,
+  `REMOTE_RADIUS_AUTH_ENABLED` int(11) NOT NULL,
+  `REMOTE_TACACS_AUTH_ENABLED` int(11) NOT NULL,
+  `NXDOMAIN_ENABLED` int(11) NOT NULL,
+  `NCR_ENCODE_ENABLED` int(11) NOT NULL,
+  `HTTP_MODE_ENABLED` int(11) NOT NULL,
+  `TRHA_PASSPHRASE` varchar(255) DEFAULT NULL,
+  `TRHA_ENCRYPTION_ENABLED` int(11) NOT NULL,
+  `DEMO_MODE` int(11) NOT NULL,
+  `DVT_UNINSTALL` int(11) NOT NULL,
+  `STACKING_ENABLED` int(11) NOT NULL,
+  `STACK_REDUNDANCY` varchar(255) DEFAULT NULL,
+  `REGISTERED_TIME` int(11) NOT NULL,
+  `REGISTER_INSTANCE_ID` varchar(255) DEFAULT NULL,
+  `REGISTER_PROVIDER` varchar(255) DEFAULT NULL,
+  `REGISTER_LICENSE_TYPE` varchar(255) DEFAULT NULL,
+  `GENERIC_TELEMETRY_ENABLED` int(11) NOT NULL,
+  `VNS_INSTALLED` int(11) NOT NULL,
+  `VNS_REBOOT_REQUIRED` int(11) NOT NULL,
+  `APPLY_DOMAIN_REP_TO_HTTP_TRAFFIC` int(11) NOT NULL,
+  `APPLY_DOMAIN_REP_TO_TLS_SNI` int(11) NOT NULL,
+  `APPLY_DOMAIN_REP_TO_DNS_TRAFFIC` int(11) NOT NULL,
+  `SHORT_ID` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `TPT_DEVICE` VALUES ('dev-1','ips1',NULL,NULL,'6.0.0',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'10.0.0.1',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,0,0,0,0,0,0,NULL,NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,0,NULL,0,0,0,0,0,0,NULL,0,0,0,0,0,NULL,0,0,0,0,NULL,0,NULL,NULL,NULL,0,0,0,0,0,0,1),('dev-2','ips2',NULL,NULL,'6.1.0',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'10.0.0.2',NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,0,0,0,0,0,0,0,0,NULL,NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,0,NULL,0,0,0,0,0,0,NULL,0,0,0,0,0,NULL,0,0,0,0,NULL,0,NULL,NULL,NULL,0,0,0,0,0,0,2);
+DROP TABLE IF EXISTS `NAMED_CERTIFICATE`;
+CREATE TABLE `NAMED_CERTIFICATE` (
+  `ID` int(11) NOT NULL,
+  `UUID` varchar(255) NOT NULL,
+  `NAME` varchar(255) NOT NULL,
+  `CA` int(11) NOT NULL,
+  `THUMBPRINT` varchar(255) NOT NULL,
+  `CERT_BYTES` blob,
+  `CRL_STATUS` varchar(255) DEFAULT NULL,
+  `CRL_LAST_UPDATED` varchar(255) DEFAULT NULL,
+  `EXPORTABLE` int(11) NOT NULL,
+  `PRIVATE_KEY_EXPECTED` int(11) NOT NULL,
+  `DEFAULT_CA` int(11) NOT NULL,
+  `ACME_INFO` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `NAMED_CERTIFICATE` VALUES (10,'cert-10','inventory',0,'AB:CD','-----BEGIN CERTIFICATE-----\nMIIBmDCCAT2gAwIBAgIUAxWgBQoeo5Olq6mKXCGFxOtQGGAwCgYIKoZIzj0EAwIw\nIDEeMBwGA1UEAwwVaW52ZW50b3J5LmV4YW1wbGUuY29tMCAXDTI2MTAxODA4MzIy\nMFoYDzIxMjYwOTI0MDgzMjIwWjAgMR4wHAYDVQQDDBVpbnZlbnRvcnkuZXhhbXBs\nZS5jb20wWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAASgtfLvqcKk4AXYHeVCLPfC\ngYBAmUtxIdoPc3wenINGLBcAwiiOtO9O+seRBGlheI23VHe5RDQCcDfP8trDy5wV\no1MwUTAdBgNVHQ4EFgQUuI1d45h/12gCd8Nntdyh7s8PAoQwHwYDVR0jBBgwFoAU\nuI1d45h/12gCd8Nntdyh7s8PAoQwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQD\nAgNJADBGAiEAmyPuTCuOo9hruQpUYHaMIVi0njqmCCoCWrC0u1jwa20CIQCPpTWX\nWoPoVkwXg9vUkolp9ZmqCoKMtrCkJTtCs3zO0Q==\n-----END CERTIFICATE-----\n',NULL,NULL,0,1,0,NULL);
+DROP TABLE IF EXISTS `DEVICE_CERTIFICATE`;
+CREATE TABLE `DEVICE_CERTIFICATE` (
+  `ID` int(11) NOT NULL,
+  `VERSION` int(11) NOT NULL,
+  `DEVICE_SHORT_ID` int(11) NOT NULL,
+  `DEVICE_CERT_NAME` varchar(255) NOT NULL,
+  `NAMED_CERTIFICATE_ID` int(11) NOT NULL,
+  `USE_AUTHENTICATION` int(11) NOT NULL,
+  `USE_IPSEC_VPN` int(11) NOT NULL,
+  `USE_SSL_INSPECTION` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `DEVICE_CERTIFICATE` VALUES (1,0,1,'inventory',10,0,0,1),(2,0,2,'inventory',10,0,0,1);
+DROP TABLE IF EXISTS `SSL_SERVER`;
+CREATE TABLE `SSL_SERVER` (
+  `SSL_SERVER_ID` varchar(255) NOT NULL,
+  `NAME` varchar(255) NOT NULL,
+  `DESCRIPTION` varchar(255) DEFAULT NULL,
+  `SERVICE_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `LOGGING_ENABLED` varchar(255) DEFAULT NULL,
+  `TCP_RESET_ENABLED` varchar(255) DEFAULT NULL,
+  `DOWNGRADE_HTTP2_ENABLED` varchar(255) DEFAULT NULL,
+  `ITERATION_ID` int(11) NOT NULL,
+  `LAST_UPDATED` varchar(255) NOT NULL,
+  `VERSION` varchar(255) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_SERVER` VALUES ('server-web','web',NULL,NULL,NULL,NULL,NULL,0,'',''),('server-mail','mail',NULL,NULL,NULL,NULL,NULL,0,'','');
+DROP TABLE IF EXISTS `SSL_SERVER_CERTIFICATES`;
+CREATE TABLE `SSL_SERVER_CERTIFICATES` (
+  `SSL_SERVER_ID` varchar(255) NOT NULL,
+  `NAMED_CERTIFICATE_ID` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_SERVER_CERTIFICATES` VALUES ('server-web',10),('server-mail',10);
+DROP TABLE IF EXISTS `SSL_SERVER_PORT`;
+CREATE TABLE `SSL_SERVER_PORT` (
+  `ID` int(11) NOT NULL,
+  `SSL_SERVER_ID` varchar(255) NOT NULL,
+  `PROTOCOL_TYPE` varchar(255) NOT NULL,
+  `START_PORT` int(11) NOT NULL,
+  `END_PORT` varchar(255) DEFAULT NULL,
+  `VERSION` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_SERVER_PORT` VALUES (1,'server-web','HTTP',443,NULL,0),(2,'server-mail','SMTP',465,NULL,0);
+DROP TABLE IF EXISTS `SSL_CLIENT_PROXY`;
+CREATE TABLE `SSL_CLIENT_PROXY` (
+  `SSL_CLIENT_PROXY_ID` varchar(255) NOT NULL,
+  `NAME` varchar(255) NOT NULL,
+  `DESCRIPTION` varchar(255) DEFAULT NULL,
+  `NAMED_CERTIFICATE_ID` int(11) NOT NULL,
+  `SERVICE_NAMED_OBJ_ID` varchar(255) DEFAULT NULL,
+  `MIN_KEY_LENGTH` varchar(255) DEFAULT NULL,
+  `LOGGING_ENABLED` int(11) NOT NULL,
+  `TCP_RESET_ENABLED` int(11) NOT NULL,
+  `BLOCK_INV_CERT_ENABLED` int(11) NOT NULL,
+  `BLOCK_EXP_CERT_ENABLED` int(11) NOT NULL,
+  `DOWNGRADE_HTT2_ENABLED` int(11) NOT NULL,
+  `ITERATION_ID` int(11) NOT NULL,
+  `LAST_UPDATED` varchar(255) NOT NULL,
+  `VERSION` varchar(255) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CLIENT_PROXY` VALUES ('proxy-outbound','outbound',NULL,10,NULL,NULL,0,0,0,1,0,0,'','');
+DROP TABLE IF EXISTS `SSL_CLIENT_PROXY_PORT`;
+CREATE TABLE `SSL_CLIENT_PROXY_PORT` (
+  `ID` int(11) NOT NULL,
+  `SSL_CLIENT_PROXY_ID` varchar(255) NOT NULL,
+  `PROTOCOL_TYPE` varchar(255) NOT NULL,
+  `START_PORT` int(11) NOT NULL,
+  `END_PORT` varchar(255) DEFAULT NULL,
+  `VERSION` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+DROP TABLE IF EXISTS `POLICY`;
+CREATE TABLE `POLICY` (
+  `PROFILE_ID` varchar(255) NOT NULL,
+  `POLICYID` varchar(255) NOT NULL,
+  `DISPLAYNAME` varchar(255) DEFAULT NULL,
+  `SIGID` varchar(255) DEFAULT NULL,
+  `MESSAGE` varchar(255) DEFAULT NULL,
+  `ACTIVE` int(11) NOT NULL,
+  `SEVERITY` varchar(255) DEFAULT NULL,
+  `PRECEDENCE` varchar(255) DEFAULT NULL,
+  `ACTIONSET_ID` varchar(255) DEFAULT NULL,
+  `DIRECTION` varchar(255) DEFAULT NULL,
+  `REQ_CAPABILITIES` varchar(255) DEFAULT NULL,
+  `RECOMMENDED_ACTIONSET_ID` varchar(255) DEFAULT NULL,
+  `RECOMMENDED_STATE` varchar(255) DEFAULT NULL,
+  `ADAPTIVE_STATE` varchar(255) DEFAULT NULL,
+  `MULTIPART_GROUP_ID` varchar(255) DEFAULT NULL,
+  `GROUP_ORDER_NUMBER` varchar(255) DEFAULT NULL,
+  `USER_DEFINED` varchar(255) NOT NULL,
+  `DELETED` varchar(255) NOT NULL,
+  `ITERATION_ID` varchar(255) NOT NULL,
+  `VERSION` varchar(255) DEFAULT NULL,
+  `PKG_DIR` varchar(255) NOT NULL,
+  `UPDATE_TIME` int(11) NOT NULL,
+  `REP_FILTER_UUID` varchar(255) DEFAULT NULL,
+  `FILTER_TYPE` int(11) NOT NULL,
+  `NGFW_VERSION` varchar(255) DEFAULT NULL,
+  `PKG_ID` varchar(255) DEFAULT NULL,
+  `SSL_SERVER_ID` varchar(255) DEFAULT NULL,
+  `SSL_CLIENT_PROXY_ID` varchar(255) DEFAULT NULL,
+  `SSL_CLIENT_DECRYPT_ID` varchar(255) DEFAULT NULL,
+  `SSL_CLIENT_TRUSTSTORE_ID` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `POLICY` VALUES ('profile-web','policy-1',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,'server-web',NULL,NULL,NULL),('profile-mail','policy-2',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,'server-mail',NULL,NULL,NULL),('profile-web','policy-3',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL),('profile-outbound','policy-4',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,NULL,'proxy-outbound',NULL,NULL),('profile-outbound','policy-5',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,NULL,NULL,'decrypt-bank',NULL),('profile-web','policy-6',NULL,NULL,NULL,0,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,'','','',NULL,'',0,NULL,0,NULL,NULL,NULL,NULL,'decrypt-news',NULL);
+DROP TABLE IF EXISTS `PROFILE_INSTALL_INVENTORY`;
+CREATE TABLE `PROFILE_INSTALL_INVENTORY` (
+  `PROFILE_ID` varchar(255) NOT NULL,
+  `PROFILE_VERSION` varchar(255) NOT NULL,
+  `OBJECT_TYPE` int(11) NOT NULL,
+  `OBJECT_ID` varchar(255) NOT NULL,
+  `VIRTUAL_SEGMENT_ID` varchar(255) DEFAULT NULL,
+  `DISTRIBUTE_ID` varchar(255) NOT NULL,
+  `COMPLETE_TIME` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `PROFILE_INSTALL_INVENTORY` VALUES ('profile-web','',0,'',100,'',0),('profile-mail','',0,'',200,'',0),('profile-unused','',0,'',NULL,'',0);
+DROP TABLE IF EXISTS `SSL_CLIENT_DECRYPT`;
+CREATE TABLE `SSL_CLIENT_DECRYPT` (
+  `SSL_CLIENT_DECRYPT_ID` varchar(255) NOT NULL,
+  `NAME` varchar(255) NOT NULL,
+  `DESCRIPTION` varchar(255) DEFAULT NULL,
+  `ITERATION_ID` int(11) NOT NULL,
+  `LAST_UPDATED` varchar(255) NOT NULL,
+  `VERSION` varchar(255) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CLIENT_DECRYPT` VALUES ('decrypt-bank','bank',NULL,0,'',''),('decrypt-news','news',NULL,0,'','');
+DROP TABLE IF EXISTS `SSL_CLIENT_DECRYPT_DOMAIN`;
+CREATE TABLE `SSL_CLIENT_DECRYPT_DOMAIN` (
+  `ID` int(11) NOT NULL,
+  `SSL_CLIENT_DECRYPT_ID` varchar(255) NOT NULL,
+  `DECRYPT_TYPE` int(11) NOT NULL,
+  `DOMAIN_NAME` varchar(255) NOT NULL,
+  `VERSION` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CLIENT_DECRYPT_DOMAIN` VALUES (1,'decrypt-bank',0,'bank.example.com',0),(2,'decrypt-news',0,'news.example.com',0);
+DROP TABLE IF EXISTS `SSL_CATEGORY`;
+CREATE TABLE `SSL_CATEGORY` (
+  `SSL_CATEGORY_ID` int(11) NOT NULL,
+  `TMC_CATEGORY_ID` varchar(255) DEFAULT NULL,
+  `NAME` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CATEGORY` VALUES (5,NULL,'Finance');
+DROP TABLE IF EXISTS `SSL_CLIENT_DECRYPT_CAT`;
+CREATE TABLE `SSL_CLIENT_DECRYPT_CAT` (
+  `ID` int(11) NOT NULL,
+  `SSL_CATEGORY_ID` int(11) NOT NULL,
+  `SSL_CLIENT_DECRYPT_ID` varchar(255) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CLIENT_DECRYPT_CAT` VALUES (1,5,'decrypt-bank');
+DROP TABLE IF EXISTS `SSL_CLIENT_DECRYPT_NAMED_OBJ`;
+CREATE TABLE `SSL_CLIENT_DECRYPT_NAMED_OBJ` (
+  `ID` int(11) NOT NULL,
+  `SSL_CLIENT_DECRYPT_ID` varchar(255) NOT NULL,
+  `NAMED_OBJ_ID` int(11) NOT NULL,
+  `NAME` varchar(255) DEFAULT NULL,
+  `VERSION` int(11) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `SSL_CLIENT_DECRYPT_NAMED_OBJ` VALUES (1,'decrypt-bank',7,'branch',0);
+DROP TABLE IF EXISTS `VIRTUAL_SEGMENT`;
+CREATE TABLE `VIRTUAL_SEGMENT` (
+  `ID` int(11) NOT NULL,
+  `NAME` varchar(255) DEFAULT NULL,
+  `A_SIDE_NAME` varchar(255) DEFAULT NULL,
+  `B_SIDE_NAME` varchar(255) DEFAULT NULL,
+  `TPT_SEGMENT_UUID` varchar(255) DEFAULT NULL,
+  `DEVICE_SHORT_ID` varchar(255) DEFAULT NULL,
+  `DIRECTION` varchar(255) DEFAULT NULL,
+  `SECURITY_ZONE_PAIR_UUID` varchar(255) DEFAULT NULL,
+  `SECURITY_ZONE_SRC_UUID` varchar(255) DEFAULT NULL,
+  `SECURITY_ZONE_DST_UUID` varchar(255) DEFAULT NULL,
+  `TPT_GROUP_UUID` varchar(255) DEFAULT NULL,
+  `DESCRIPTION` varchar(255) DEFAULT NULL,
+  `GLOBAL_VIRTUAL_SEGMENT_ID` varchar(255) DEFAULT NULL,
+  `DEVICE_VSEG_POSITION` varchar(255) DEFAULT NULL,
+  `BAY_NUMBER` varchar(255) DEFAULT NULL,
+  `DELETED_SEGMENT` varchar(255) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+INSERT INTO `VIRTUAL_SEGMENT` VALUES (100,'ips1 segment',NULL,NULL,NULL,1,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL),(200,'ips2 segment',NULL,NULL,NULL,2,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL,NULL);
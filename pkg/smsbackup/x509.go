@@ -0,0 +1,145 @@
+package smsbackup
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// fingerprint returns uppercase hex of the hash.
+func fingerprint(sum []byte) string {
+	return strings.ToUpper(hex.EncodeToString(sum))
+}
+
+// sha256Fingerprint returns SHA-256 fingerprint of the DER encoded certificate.
+func sha256Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return fingerprint(sum[:])
+}
+
+// sha1Fingerprint returns SHA-1 fingerprint of the DER encoded certificate.
+func sha1Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return fingerprint(sum[:])
+}
+
+// keyAlgorithm returns public key algorithm with curve name for ECDSA.
+func keyAlgorithm(cert *x509.Certificate) string {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA"
+	case *ecdsa.PublicKey:
+		return "ECDSA " + pub.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// subjectAltNames returns SANs prefixed by their type the same way as openssl does.
+func subjectAltNames(cert *x509.Certificate) string {
+	var names []string
+	for _, name := range cert.DNSNames {
+		names = append(names, "DNS:"+name)
+	}
+	for _, ip := range cert.IPAddresses {
+		names = append(names, "IP:"+ip.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		names = append(names, "email:"+email)
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, "URI:"+uri.String())
+	}
+	return strings.Join(names, ",")
+}
+
+// basicConstraints returns basic constraints in openssl notation or empty
+// string if certificate has no such extension.
+func basicConstraints(cert *x509.Certificate) string {
+	if !cert.BasicConstraintsValid {
+		return ""
+	}
+	if !cert.IsCA {
+		return "CA:FALSE"
+	}
+	if cert.MaxPathLen > 0 || cert.MaxPathLenZero {
+		return "CA:TRUE,pathlen:" + strconv.Itoa(cert.MaxPathLen)
+	}
+	return "CA:TRUE"
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Non Repudiation"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+// keyUsages returns names of the key usages.
+func keyUsages(cert *x509.Certificate) (names []string) {
+	for _, u := range keyUsageNames {
+		if cert.KeyUsage&u.usage != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return
+}
+
+// keyUsage returns comma separated names of the key usages.
+func keyUsage(cert *x509.Certificate) string {
+	return strings.Join(keyUsages(cert), ",")
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any",
+	x509.ExtKeyUsageServerAuth:                     "Server Authentication",
+	x509.ExtKeyUsageClientAuth:                     "Client Authentication",
+	x509.ExtKeyUsageCodeSigning:                    "Code Signing",
+	x509.ExtKeyUsageEmailProtection:                "Email Protection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSec End System",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSec Tunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSec User",
+	x509.ExtKeyUsageTimeStamping:                   "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSP Signing",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "Microsoft Server Gated Crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "Netscape Server Gated Crypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "Microsoft Commercial Code Signing",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "Microsoft Kernel Code Signing",
+}
+
+// extKeyUsages returns names of the extended key usages. Unknown usages are
+// listed by their OIDs.
+func extKeyUsages(cert *x509.Certificate) (names []string) {
+	for _, u := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[u]
+		if !ok {
+			name = "Unknown (" + strconv.Itoa(int(u)) + ")"
+		}
+		names = append(names, name)
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return
+}
+
+// extKeyUsage returns comma separated names of the extended key usages.
+func extKeyUsage(cert *x509.Certificate) string {
+	return strings.Join(extKeyUsages(cert), ",")
+}
@@ -0,0 +1,77 @@
+package smsbackup
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetX509(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, _ := url.Parse("spiffe://example.com/web")
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.com"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"example.com", "www.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses:        []string{"admin@example.com"},
+		URIs:                  []*url.URL{uri},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		SubjectKeyId:          []byte{0xab, 0xcd},
+		CRLDistributionPoints: []string{"http://example.com/crl"},
+		OCSPServer:            []string{"http://ocsp.example.com"},
+		IssuingCertificateURL: []string{"http://example.com/ca.crt"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r ReportLine
+	if err := r.GetX509(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), ReportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(der)
+	expected := ReportLine{
+		KeySize0:               "256",
+		KeyAlgorithm:           "Ed25519",
+		SubjectAltNames:        "DNS:example.com,DNS:www.example.com,IP:10.0.0.1,email:admin@example.com,URI:spiffe://example.com/web",
+		SHA256Fingerprint:      fingerprint(sum[:]),
+		BasicConstraints:       "CA:TRUE,pathlen:0",
+		KeyUsage:               "Digital Signature,Certificate Sign",
+		ExtKeyUsage:            "Server Authentication",
+		SubjectKeyID:           "ABCD", // self-signed, so no AKI
+		CRLDistributionPoints:  "http://example.com/crl",
+		OCSPServers:            "http://ocsp.example.com",
+		IssuingCertificateURLs: "http://example.com/ca.crt",
+	}
+	r.IssuerName, r.ExpirationDate, r.EffectiveDate, r.SerialNumber = "", "", "", ""
+	r.SignatureAlgorithm, r.SubjectName, r.Version = "", "", ""
+	r.DaysToExpiry, r.Status = "", ""
+	r.cert = nil
+	if len(r.SHA1Fingerprint) != 40 || strings.ToUpper(r.SHA1Fingerprint) != r.SHA1Fingerprint {
+		t.Errorf("wrong SHA-1 fingerprint %q", r.SHA1Fingerprint)
+	}
+	r.SHA1Fingerprint = ""
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %+v, got %+v", expected, r)
+	}
+}
@@ -0,0 +1,268 @@
+package smsbackup
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrPassphrase       = errors.New("backup is encrypted, passphrase is required")
+	ErrWrongPassphrase  = errors.New("wrong passphrase")
+	ErrUnsupportedZip   = errors.New("unsupported ZIP entry")
+	ErrAuthentication   = errors.New("backup authentication failed")
+	ErrCorruptedPadding = errors.New("wrong padding of decrypted backup")
+)
+
+const (
+	zipEncryptedFlag      = 0x1
+	zipDataDescriptorFlag = 0x8
+	// WinZip AES encryption (AE-1, AE-2)
+	zipMethodAES  = 99
+	zipAESExtraID = 0x9901
+	// Lengths of WinZip AES fields
+	aesVerifierLength = 2
+	aesAuthLength     = 10
+	aesIterations     = 1000
+)
+
+// openZipFile opens ZIP entry decrypting it if needed.
+func openZipFile(file *zip.File, passphrase string) (io.ReadCloser, error) {
+	if file.Flags&zipEncryptedFlag == 0 {
+		return file.Open()
+	}
+	if passphrase == "" {
+		return nil, ErrPassphrase
+	}
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	method := file.Method
+	var r io.Reader
+	if method == zipMethodAES {
+		r, method, err = newAESReader(file, raw, passphrase)
+	} else {
+		r, err = newZipCryptoReader(file, raw, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser
+	switch method {
+	case zip.Store:
+		rc = io.NopCloser(r)
+	case zip.Deflate:
+		rc = flate.NewReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %s: compression method %d", ErrUnsupportedZip, file.Name, method)
+	}
+	return &checksumReader{rc: rc, hash: crc32.NewIEEE(), crc: file.CRC32}, nil
+}
+
+// checksumReader checks CRC32 of the decompressed data at EOF. AE-2 entries
+// have zero CRC32, they are checked by HMAC.
+type checksumReader struct {
+	rc   io.ReadCloser
+	hash hash.Hash32
+	crc  uint32
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.rc.Read(p)
+	c.hash.Write(p[:n])
+	if err == io.EOF && c.crc != 0 && c.hash.Sum32() != c.crc {
+		return n, zip.ErrChecksum
+	}
+	return n, err
+}
+
+func (c *checksumReader) Close() error {
+	return c.rc.Close()
+}
+
+// zipCrypto is traditional PKWARE encryption.
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func newZipCrypto(passphrase string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for _, b := range []byte(passphrase) {
+		z.update(b)
+	}
+	return z
+}
+
+func (z *zipCrypto) update(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+z.keys[0]&0xff)*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCrypto) decrypt(p []byte) {
+	for i, c := range p {
+		temp := z.keys[2] | 2
+		p[i] = c ^ byte((temp*(temp^1))>>8)
+		z.update(p[i])
+	}
+}
+
+type zipCryptoReader struct {
+	r io.Reader
+	z *zipCrypto
+}
+
+func (z *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := z.r.Read(p)
+	z.z.decrypt(p[:n])
+	return n, err
+}
+
+// newZipCryptoReader checks passphrase using 12 bytes encryption header and
+// returns reader of decrypted data.
+func newZipCryptoReader(file *zip.File, raw io.Reader, passphrase string) (io.Reader, error) {
+	z := newZipCrypto(passphrase)
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	z.decrypt(header)
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&zipDataDescriptorFlag != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+	if header[11] != check {
+		return nil, ErrWrongPassphrase
+	}
+	return &zipCryptoReader{r: raw, z: z}, nil
+}
+
+// aesExtra returns key length and actual compression method from WinZip AES
+// extra field.
+func aesExtra(file *zip.File) (keyLength int, method uint16, err error) {
+	extra := file.Extra
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if id != zipAESExtraID || size < 7 {
+			extra = extra[size:]
+			continue
+		}
+		strength := extra[4]
+		method = binary.LittleEndian.Uint16(extra[5:])
+		switch strength {
+		case 1, 2, 3:
+			return 8 + 8*int(strength), method, nil
+		}
+		return 0, 0, fmt.Errorf("%w: %s: AES strength %d", ErrUnsupportedZip, file.Name, strength)
+	}
+	return 0, 0, fmt.Errorf("%w: %s: no AES extra field", ErrUnsupportedZip, file.Name)
+}
+
+// newAESReader checks passphrase and returns reader of decrypted data and
+// actual compression method of WinZip AES encrypted entry.
+func newAESReader(file *zip.File, raw io.Reader, passphrase string) (io.Reader, uint16, error) {
+	keyLength, method, err := aesExtra(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	saltLength := keyLength / 2
+	overhead := uint64(saltLength + aesVerifierLength + aesAuthLength)
+	if file.CompressedSize64 < overhead {
+		return nil, 0, fmt.Errorf("%w: %s: too short", ErrUnsupportedZip, file.Name)
+	}
+	header := make([]byte, saltLength+aesVerifierLength)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, 0, err
+	}
+	salt, verifier := header[:saltLength], header[saltLength:]
+	keys := pbkdf2.Key([]byte(passphrase), salt, aesIterations, 2*keyLength+aesVerifierLength, sha1.New)
+	if !bytes.Equal(keys[2*keyLength:], verifier) {
+		return nil, 0, ErrWrongPassphrase
+	}
+	block, err := aes.NewCipher(keys[:keyLength])
+	if err != nil {
+		return nil, 0, err
+	}
+	return &aesReader{
+		raw:    raw,
+		r:      io.LimitReader(raw, int64(file.CompressedSize64-overhead)),
+		stream: newWinZipCTR(block),
+		mac:    hmac.New(sha1.New, keys[keyLength:2*keyLength]),
+	}, method, nil
+}
+
+// aesReader decrypts WinZip AES entry and checks its authentication code at EOF.
+type aesReader struct {
+	raw    io.Reader
+	r      io.Reader
+	stream cipher.Stream
+	mac    hash.Hash
+}
+
+func (a *aesReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	a.mac.Write(p[:n])
+	a.stream.XORKeyStream(p[:n], p[:n])
+	if err != io.EOF {
+		return n, err
+	}
+	auth := make([]byte, aesAuthLength)
+	if _, err := io.ReadFull(a.raw, auth); err != nil {
+		return n, err
+	}
+	if !hmac.Equal(a.mac.Sum(nil)[:aesAuthLength], auth) {
+		return n, ErrAuthentication
+	}
+	return n, io.EOF
+}
+
+// winZipCTR is CTR mode with little endian counter starting from 1 used by WinZip AES.
+type winZipCTR struct {
+	block     cipher.Block
+	counter   [aes.BlockSize]byte
+	keyStream [aes.BlockSize]byte
+	used      int
+}
+
+func newWinZipCTR(block cipher.Block) *winZipCTR {
+	return &winZipCTR{block: block, used: aes.BlockSize}
+}
+
+func (w *winZipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if w.used == aes.BlockSize {
+			for j := range w.counter {
+				w.counter[j]++
+				if w.counter[j] != 0 {
+					break
+				}
+			}
+			w.block.Encrypt(w.keyStream[:], w.counter[:])
+			w.used = 0
+		}
+		dst[i] = src[i] ^ w.keyStream[w.used]
+		w.used++
+	}
+}
@@ -0,0 +1,83 @@
+package smsbackup
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fixtures contain noalerts.mysqldump with testDump encrypted by "secret"
+// passphrase. zipcrypto.zip is made by info-zip (zip -0 -P secret).
+// aes.zip is AE-2 with AES-256 made by PBKDF2 and AES of Python hashlib and
+// openssl command line tool. Tampered fixtures have one byte of encrypted
+// data flipped.
+func readFixture(t *testing.T, name, passphrase string) (string, error) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readDump(t, data, passphrase)
+}
+
+func TestZipEncryption(t *testing.T) {
+	testCases := []struct {
+		fixture    string
+		passphrase string
+		err        error
+	}{
+		{"zipcrypto.zip", "secret", nil},
+		{"zipcrypto.zip", "wrong", ErrWrongPassphrase},
+		{"zipcrypto.zip", "", ErrPassphrase},
+		{"zipcrypto-tampered.zip", "secret", zip.ErrChecksum},
+		{"aes.zip", "secret", nil},
+		{"aes.zip", "wrong", ErrWrongPassphrase},
+		{"aes.zip", "", ErrPassphrase},
+		{"aes-tampered.zip", "secret", ErrAuthentication},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.fixture+"/"+tc.passphrase, func(t *testing.T) {
+			content, err := readFixture(t, tc.fixture, tc.passphrase)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if tc.err == nil && content != testDump {
+				t.Errorf("expected %q, got %q", testDump, content)
+			}
+		})
+	}
+}
+
+func TestWinZipCTR(t *testing.T) {
+	// Decrypting in pieces of different sizes gives the same result
+	data, err := os.ReadFile(filepath.Join("testdata", "aes.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "aes.zip")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := OpenDump(path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dump.Close()
+	var content []byte
+	buf := make([]byte, 7)
+	for {
+		n, err := dump.Read(buf)
+		content = append(content, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(content) != testDump {
+		t.Errorf("expected %q, got %q", testDump, content)
+	}
+}